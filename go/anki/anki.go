@@ -0,0 +1,106 @@
+package anki
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Reads and writes Anki's .apkg packages: a zip holding the collection, an SQLite database
+// in the schema 11 layout every Anki version since 2.1 can open, and a media index. Only
+// the cards are carried over; media and card templates stay behind.
+
+// Note is one imported card. Fields are plain text, Anki's html is converted on the way in
+type Note struct {
+	Front string
+	Back  string
+}
+
+// Deck is what an import produces. Skipped counts notes with an empty side after conversion
+type Deck struct {
+	Name        string
+	Description string
+	Notes       []Note
+	Skipped     int
+}
+
+// Fields picks the note fields used for each side by name. Empty names, or a name the
+// note type doesn't have, fall back to its first and second fields
+type Fields struct {
+	Front string
+	Back  string
+}
+
+// Card is one card to export. Schedule and Reviews are optional; without them the card
+// arrives in Anki as new
+type Card struct {
+	ID       int32
+	Front    string
+	Back     string
+	Schedule *Schedule
+	Reviews  []Review
+}
+
+// Schedule is the card's current SM-2 state
+type Schedule struct {
+	EaseFactor   float64
+	IntervalDays int
+	Repetitions  int
+	Lapses       int
+	DueAt        time.Time
+}
+
+// Review is one past answer, oldest first. Grade is again, hard, good or easy
+type Review struct {
+	Grade        string
+	ResponseMs   int
+	IntervalDays int
+	EaseFactor   float64
+	ReviewedAt   time.Time
+}
+
+// Export is a whole set on its way out
+type Export struct {
+	SetID       int32
+	Name        string
+	Description string
+	Cards       []Card
+}
+
+var (
+	lineBreaks  = regexp.MustCompile(`(?i)<br\s*/?>|</(div|p|li|tr)>`)
+	tags        = regexp.MustCompile(`<[^>]*>`)
+	sounds      = regexp.MustCompile(`\[sound:[^\]]*\]`)
+	extraBlanks = regexp.MustCompile(`\n{3,}`)
+	clozes      = regexp.MustCompile(`(?s)\{\{c\d+::(.*?)(?:::(.*?))?\}\}`)
+)
+
+// toText turns a field's html into plain text, keeping line breaks
+func toText(field string) string {
+	field = sounds.ReplaceAllString(field, "")
+	field = lineBreaks.ReplaceAllString(field, "\n")
+	field = tags.ReplaceAllString(field, "")
+	field = html.UnescapeString(field)
+	field = strings.ReplaceAll(field, " ", " ")
+	field = extraBlanks.ReplaceAllString(field, "\n\n")
+	return strings.TrimSpace(field)
+}
+
+// toHTML is the other way, Anki shows fields as html
+func toHTML(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
+
+// clozeSides blanks every deletion (showing its hint if it has one) for the front and fills
+// them all in for the back
+func clozeSides(text string) (front, back string) {
+	front = clozes.ReplaceAllStringFunc(text, func(m string) string {
+		if hint := clozes.FindStringSubmatch(m)[2]; hint != "" {
+			return "[" + hint + "]"
+		}
+		return "[...]"
+	})
+	back = clozes.ReplaceAllString(text, "$1")
+	return front, back
+}
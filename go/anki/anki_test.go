@@ -0,0 +1,140 @@
+package anki
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteRead(t *testing.T) {
+	reviewed := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	export := Export{
+		SetID:       7,
+		Name:        "Spanish 1",
+		Description: "Unit 1\nGreetings & <basics>",
+		Cards: []Card{
+			{ID: 1, Front: "hola", Back: "hello"},
+			{ID: 2, Front: "buenos días", Back: "good morning\n(before noon)", Schedule: &Schedule{
+				EaseFactor: 2.6, IntervalDays: 6, Repetitions: 2, DueAt: reviewed.Add(6 * day),
+			}, Reviews: []Review{
+				{Grade: "good", ResponseMs: 1200, IntervalDays: 1, EaseFactor: 2.5, ReviewedAt: reviewed.Add(-day)},
+				{Grade: "easy", ResponseMs: 900, IntervalDays: 6, EaseFactor: 2.6, ReviewedAt: reviewed},
+			}},
+			{ID: 3, Front: "a < b & c", Back: "<b>not bold</b>"},
+			{ID: 4, Front: "no back", Back: ""},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, export); err != nil {
+		t.Fatal(err)
+	}
+
+	deck, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()), Fields{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deck.Name != export.Name || deck.Description != export.Description {
+		t.Errorf("got deck %q %q, want %q %q", deck.Name, deck.Description, export.Name, export.Description)
+	}
+
+	want := []Note{
+		{Front: "hola", Back: "hello"},
+		{Front: "buenos días", Back: "good morning\n(before noon)"},
+		{Front: "a < b & c", Back: "<b>not bold</b>"},
+	}
+	if !reflect.DeepEqual(deck.Notes, want) {
+		t.Errorf("got notes %q, want %q", deck.Notes, want)
+	}
+	if deck.Skipped != 1 {
+		t.Errorf("got %d skipped, want 1", deck.Skipped)
+	}
+
+	t.Run("fields by name", func(t *testing.T) {
+		deck, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()), Fields{Front: "back", Back: "FRONT"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deck.Notes) == 0 || deck.Notes[0] != (Note{Front: "hello", Back: "hola"}) {
+			t.Errorf("got notes %q, want sides swapped", deck.Notes)
+		}
+	})
+}
+
+func TestRead(t *testing.T) {
+	t.Run("not a zip", func(t *testing.T) {
+		body := []byte("not an apkg")
+		if _, err := Read(bytes.NewReader(body), int64(len(body)), Fields{}); err == nil {
+			t.Error("got nil error, want one")
+		}
+	})
+}
+
+func TestToText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{"<b>bold</b> and <i>italic</i>", "bold and italic"},
+		{"line one<br>line two<br/>line three<BR />", "line one\nline two\nline three"},
+		{"<div>first</div><div>second</div>", "first\nsecond"},
+		{"a&nbsp;b &amp; c &lt;d&gt;", "a b & c <d>"},
+		{"word [sound:word.mp3]", "word"},
+		{"<p>one</p><p></p><p></p><p></p><p>two</p>", "one\n\ntwo"},
+		{"  <br> padded <br>  ", "padded"},
+	}
+
+	for _, tt := range tests {
+		if got := toText(tt.in); got != tt.want {
+			t.Errorf("toText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestClozeSides(t *testing.T) {
+	tests := []struct {
+		in        string
+		wantFront string
+		wantBack  string
+	}{
+		{"{{c1::Madrid}} is the capital of {{c2::Spain}}", "[...] is the capital of [...]", "Madrid is the capital of Spain"},
+		{"{{c1::Paris::city}} is in France", "[city] is in France", "Paris is in France"},
+		{"no deletions", "no deletions", "no deletions"},
+	}
+
+	for _, tt := range tests {
+		front, back := clozeSides(tt.in)
+		if front != tt.wantFront || back != tt.wantBack {
+			t.Errorf("clozeSides(%q) = %q, %q, want %q, %q", tt.in, front, back, tt.wantFront, tt.wantBack)
+		}
+	}
+}
+
+func TestConvertNote(t *testing.T) {
+	var cloze model
+	err := json.Unmarshal([]byte(`{"type": 1, "flds": [{"name": "Back Extra", "ord": 1}, {"name": "Text", "ord": 0}]}`), &cloze)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("cloze with extra", func(t *testing.T) {
+		note, ok := convertNote(cloze, []string{"{{c1::<b>hola</b>}} means hello", "greeting"}, Fields{})
+		want := Note{Front: "[...] means hello", Back: "hola means hello\n\ngreeting"}
+		if !ok || note != want {
+			t.Errorf("got %q %v, want %q", note, ok, want)
+		}
+	})
+
+	t.Run("empty side is skipped", func(t *testing.T) {
+		if _, ok := convertNote(model{}, []string{"<br>", "back"}, Fields{}); ok {
+			t.Error("got ok for a note with an empty front")
+		}
+		if _, ok := convertNote(model{}, []string{"front only"}, Fields{}); ok {
+			t.Error("got ok for a note with no back field")
+		}
+	})
+}
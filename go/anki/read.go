@@ -0,0 +1,204 @@
+package anki
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	maxCollectionBytes int64 = 256 << 20
+	maxNotes           int   = 10000
+
+	clozeModel int = 1
+)
+
+// ErrNewFormat is returned for packages only newer Anki versions can read
+var ErrNewFormat = errors.New(`this package uses the newer Anki format, export it again with "Support older Anki versions" checked`)
+
+// the parts of a note type an import needs
+type model struct {
+	Type int `json:"type"`
+	Flds []struct {
+		Name string `json:"name"`
+		Ord  int    `json:"ord"`
+	} `json:"flds"`
+}
+
+type deck struct {
+	Name string `json:"name"`
+	Desc string `json:"desc"`
+}
+
+// Read converts an .apkg into a deck. Every note becomes one card: cloze notes are
+// split into a blanked front and a filled in back, anything else uses the chosen fields.
+// The deck name is the one most of the cards are in
+func Read(r io.ReaderAt, size int64, fields Fields) (Deck, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return Deck{}, fmt.Errorf("not an apkg file: %w", err)
+	}
+
+	// collection.anki21 is written alongside the legacy one when a deck uses newer features.
+	// Packages with only collection.anki21b carry a placeholder in collection.anki2
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	collection := files["collection.anki21"]
+	if collection == nil {
+		if files["collection.anki21b"] != nil {
+			return Deck{}, ErrNewFormat
+		}
+		collection = files["collection.anki2"]
+	}
+	if collection == nil {
+		return Deck{}, errors.New("no collection in the package")
+	}
+
+	path, err := extract(collection)
+	if err != nil {
+		return Deck{}, err
+	}
+	defer os.Remove(path)
+
+	conn, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return Deck{}, err
+	}
+	defer conn.Close()
+
+	return readCollection(conn, fields)
+}
+
+// extract copies the collection out of the zip, sqlite can only open files
+func extract(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp("", "apkg-*.anki2")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+
+	n, err := io.Copy(tmp, io.LimitReader(rc, maxCollectionBytes+1))
+	if err == nil && n > maxCollectionBytes {
+		err = fmt.Errorf("collection is larger than %d MB", maxCollectionBytes>>20)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+func readCollection(conn *sql.DB, fields Fields) (Deck, error) {
+	var modelsJSON, decksJSON string
+	err := conn.QueryRow(`SELECT models, decks FROM col`).Scan(&modelsJSON, &decksJSON)
+	if err != nil {
+		return Deck{}, fmt.Errorf("reading collection: %w", err)
+	}
+
+	var models map[string]model
+	if err = json.Unmarshal([]byte(modelsJSON), &models); err != nil {
+		return Deck{}, fmt.Errorf("reading note types: %w", err)
+	}
+	var decks map[string]deck
+	if err = json.Unmarshal([]byte(decksJSON), &decks); err != nil {
+		return Deck{}, fmt.Errorf("reading decks: %w", err)
+	}
+
+	var res Deck
+
+	// the deck with the most cards names the set
+	var deckID int64
+	err = conn.QueryRow(`SELECT did FROM cards GROUP BY did ORDER BY COUNT(*) DESC, did LIMIT 1`).Scan(&deckID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return Deck{}, fmt.Errorf("reading cards: %w", err)
+	}
+	if d, ok := decks[strconv.FormatInt(deckID, 10)]; ok {
+		res.Name = strings.ReplaceAll(d.Name, "::", " - ")
+		res.Description = toText(d.Desc)
+	}
+
+	rows, err := conn.Query(`SELECT mid, flds FROM notes ORDER BY id`)
+	if err != nil {
+		return Deck{}, fmt.Errorf("reading notes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			mid  int64
+			flds string
+		)
+		if err = rows.Scan(&mid, &flds); err != nil {
+			return Deck{}, fmt.Errorf("reading notes: %w", err)
+		}
+
+		note, ok := convertNote(models[strconv.FormatInt(mid, 10)], strings.Split(flds, "\x1f"), fields)
+		if !ok {
+			res.Skipped++
+			continue
+		}
+
+		res.Notes = append(res.Notes, note)
+		if len(res.Notes) > maxNotes {
+			return Deck{}, fmt.Errorf("more than %d notes", maxNotes)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return Deck{}, fmt.Errorf("reading notes: %w", err)
+	}
+
+	return res, nil
+}
+
+// convertNote maps a note's fields onto a card. ok is false if a side comes out empty
+func convertNote(m model, values []string, fields Fields) (note Note, ok bool) {
+	// field values are stored in ord order
+	names := make([]string, len(m.Flds))
+	sort.Slice(m.Flds, func(i, j int) bool { return m.Flds[i].Ord < m.Flds[j].Ord })
+	for i, f := range m.Flds {
+		names[i] = f.Name
+	}
+
+	value := func(name string, fallback int) string {
+		i := fallback
+		for j, n := range names {
+			if name != "" && strings.EqualFold(n, name) {
+				i = j
+				break
+			}
+		}
+		if i < len(values) {
+			return values[i]
+		}
+		return ""
+	}
+
+	if m.Type == clozeModel {
+		note.Front, note.Back = clozeSides(toText(value(fields.Front, 0)))
+		if extra := toText(value(fields.Back, 1)); extra != "" {
+			note.Back += "\n\n" + extra
+		}
+	} else {
+		note.Front = toText(value(fields.Front, 0))
+		note.Back = toText(value(fields.Back, 1))
+	}
+
+	return note, note.Front != "" && note.Back != ""
+}
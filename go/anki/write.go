@@ -0,0 +1,263 @@
+package anki
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	modelID        int64 = 1342697561419 // any fixed id works, Anki matches note types by id
+	defaultDeckID  int64 = 1
+	defaultConfID  int64 = 1
+	day                  = 24 * time.Hour
+	maxReviewTime  int   = 60000 // Anki caps the time of an answer at a minute
+	schemaVersion  int   = 11
+	fieldSeparator       = "\x1f"
+)
+
+// Anki's ease buttons for our grades
+var grades = map[string]int{"again": 1, "hard": 2, "good": 3, "easy": 4}
+
+// Write packages the set as an .apkg with one Front/Back note per card. Cards with a
+// schedule arrive as review cards due when they're due here, and their reviews fill the
+// revlog so Anki's stats have the whole history
+func Write(w io.Writer, export Export) error {
+	tmp, err := os.CreateTemp("", "apkg-*.anki2")
+	if err != nil {
+		return err
+	}
+	path := tmp.Name()
+	tmp.Close()
+	defer os.Remove(path)
+
+	if err = writeCollection(path, export); err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+
+	f, err := zw.Create("collection.anki2")
+	if err != nil {
+		return err
+	}
+	collection, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer collection.Close()
+	if _, err = io.Copy(f, collection); err != nil {
+		return err
+	}
+
+	// no media, but Anki expects the index
+	f, err = zw.Create("media")
+	if err != nil {
+		return err
+	}
+	if _, err = io.WriteString(f, "{}"); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func writeCollection(path string, export Export) error {
+	conn, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(schema); err != nil {
+		return fmt.Errorf("creating collection: %w", err)
+	}
+
+	now := time.Now().UTC()
+	created := collectionStart(now, export.Cards)
+	deckID := now.UnixMilli()
+
+	models, decks, dconf, conf, err := collectionJSON(export, deckID, now)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO col VALUES (1, ?, ?, ?, ?, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		created.Unix(), now.UnixMilli(), now.UnixMilli(), schemaVersion, conf, models, decks, dconf)
+	if err != nil {
+		return fmt.Errorf("writing collection: %w", err)
+	}
+
+	// ids are millisecond timestamps in Anki, the revlog's are the review times
+	base := now.UnixMilli()
+	usedRevlogIDs := map[int64]bool{}
+
+	for i, card := range export.Cards {
+		id := base + int64(i)
+		front, back := toHTML(card.Front), toHTML(card.Back)
+
+		_, err = tx.Exec(`INSERT INTO notes VALUES (?, ?, ?, ?, -1, '', ?, ?, ?, 0, '')`,
+			id, fmt.Sprintf("cowboy-cards-%d-%d", export.SetID, card.ID), modelID, now.Unix(),
+			front+fieldSeparator+back, card.Front, checksum(card.Front))
+		if err != nil {
+			return fmt.Errorf("writing note: %w", err)
+		}
+
+		// type, queue, due, ivl, factor, reps, lapses: a new card is due in note order
+		state := []any{0, 0, i + 1, 0, 0, 0, 0}
+		if s := card.Schedule; s != nil && s.Repetitions > 0 {
+			state = []any{2, 2, int(s.DueAt.Sub(created) / day), max(s.IntervalDays, 1), int(s.EaseFactor * 1000), s.Repetitions, s.Lapses}
+		}
+		_, err = tx.Exec(`INSERT INTO cards VALUES (?, ?, ?, 0, ?, -1, ?, ?, ?, ?, ?, ?, ?, 0, 0, 0, 0, '')`,
+			append([]any{id, id, deckID, now.Unix()}, state...)...)
+		if err != nil {
+			return fmt.Errorf("writing card: %w", err)
+		}
+
+		lastInterval := 0
+		for j, review := range card.Reviews {
+			revlogID := review.ReviewedAt.UnixMilli()
+			for usedRevlogIDs[revlogID] {
+				revlogID++
+			}
+			usedRevlogIDs[revlogID] = true
+
+			// learning, review, or relearning after a lapse
+			reviewType := 1
+			if j == 0 {
+				reviewType = 0
+			} else if card.Reviews[j-1].Grade == "again" {
+				reviewType = 2
+			}
+
+			_, err = tx.Exec(`INSERT INTO revlog VALUES (?, ?, -1, ?, ?, ?, ?, ?, ?)`,
+				revlogID, id, grades[review.Grade], review.IntervalDays, lastInterval,
+				int(review.EaseFactor*1000), min(review.ResponseMs, maxReviewTime), reviewType)
+			if err != nil {
+				return fmt.Errorf("writing review: %w", err)
+			}
+			lastInterval = review.IntervalDays
+		}
+	}
+
+	return tx.Commit()
+}
+
+// collectionStart is midnight of the earliest day the cards need, due dates are counted
+// in days from it
+func collectionStart(now time.Time, cards []Card) time.Time {
+	start := now
+	for _, card := range cards {
+		if card.Schedule != nil && card.Schedule.DueAt.Before(start) {
+			start = card.Schedule.DueAt
+		}
+		for _, review := range card.Reviews {
+			if review.ReviewedAt.Before(start) {
+				start = review.ReviewedAt
+			}
+		}
+	}
+	return start.UTC().Truncate(day)
+}
+
+// checksum is the first 8 hex digits of the sort field's sha1, Anki uses it to find duplicates
+func checksum(sortField string) int64 {
+	sum := sha1.Sum([]byte(sortField))
+	n, _ := strconv.ParseInt(hex.EncodeToString(sum[:4]), 16, 64)
+	return n
+}
+
+func collectionJSON(export Export, deckID int64, now time.Time) (models, decks, dconf, conf string, err error) {
+	field := func(name string, ord int) map[string]any {
+		return map[string]any{"name": name, "ord": ord, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []any{}}
+	}
+	deckJSON := func(id int64, name, desc string) map[string]any {
+		return map[string]any{
+			"id": id, "name": name, "desc": desc, "conf": defaultConfID, "dyn": 0, "collapsed": false,
+			"extendNew": 10, "extendRev": 50, "mod": now.Unix(), "usn": -1,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+		}
+	}
+
+	parts := []any{
+		map[string]any{strconv.FormatInt(modelID, 10): map[string]any{
+			"id": modelID, "name": "Cowboy Cards", "type": 0, "mod": now.Unix(), "usn": -1, "sortf": 0, "did": deckID,
+			"flds": []any{field("Front", 0), field("Back", 1)},
+			"tmpls": []any{map[string]any{
+				"name": "Card 1", "ord": 0, "did": nil, "bqfmt": "", "bafmt": "",
+				"qfmt": "{{Front}}", "afmt": "{{FrontSide}}\n\n<hr id=answer>\n\n{{Back}}",
+			}},
+			"css":       ".card { font-family: arial; font-size: 20px; text-align: center; color: black; background-color: white; }",
+			"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+			"latexPost": "\\end{document}",
+			"tags":      []any{}, "vers": []any{}, "req": []any{[]any{0, "any", []int{0}}},
+		}},
+		map[string]any{
+			strconv.FormatInt(defaultDeckID, 10): deckJSON(defaultDeckID, "Default", ""),
+			strconv.FormatInt(deckID, 10):        deckJSON(deckID, export.Name, toHTML(export.Description)),
+		},
+		map[string]any{strconv.FormatInt(defaultConfID, 10): map[string]any{
+			"id": defaultConfID, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true, "timer": 0, "replayq": true, "dyn": false,
+			"new":   map[string]any{"delays": []int{1, 10}, "ints": []int{1, 4, 7}, "initialFactor": 2500, "order": 1, "perDay": 20, "bury": true, "separate": true},
+			"rev":   map[string]any{"perDay": 200, "ease4": 1.3, "fuzz": 0.05, "maxIvl": 36500, "ivlFct": 1, "minSpace": 1, "bury": true},
+			"lapse": map[string]any{"delays": []int{10}, "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 0},
+		}},
+		map[string]any{
+			"activeDecks": []int64{deckID}, "curDeck": deckID, "curModel": strconv.FormatInt(modelID, 10), "nextPos": len(export.Cards) + 1,
+			"newSpread": 0, "collapseTime": 1200, "timeLim": 0, "estTimes": true, "dueCounts": true, "sortType": "noteFld", "sortBackwards": false, "addToCur": true,
+		},
+	}
+
+	out := make([]string, len(parts))
+	for i, part := range parts {
+		b, err := json.Marshal(part)
+		if err != nil {
+			return "", "", "", "", err
+		}
+		out[i] = string(b)
+	}
+	return out[0], out[1], out[2], out[3], nil
+}
+
+const schema = `
+CREATE TABLE col (
+  id integer primary key, crt integer not null, mod integer not null, scm integer not null, ver integer not null,
+  dty integer not null, usn integer not null, ls integer not null, conf text not null, models text not null,
+  decks text not null, dconf text not null, tags text not null
+);
+CREATE TABLE notes (
+  id integer primary key, guid text not null, mid integer not null, mod integer not null, usn integer not null,
+  tags text not null, flds text not null, sfld integer not null, csum integer not null, flags integer not null,
+  data text not null
+);
+CREATE TABLE cards (
+  id integer primary key, nid integer not null, did integer not null, ord integer not null, mod integer not null,
+  usn integer not null, type integer not null, queue integer not null, due integer not null, ivl integer not null,
+  factor integer not null, reps integer not null, lapses integer not null, left integer not null, odue integer not null,
+  odid integer not null, flags integer not null, data text not null
+);
+CREATE TABLE revlog (
+  id integer primary key, cid integer not null, usn integer not null, ease integer not null, ivl integer not null,
+  lastIvl integer not null, factor integer not null, time integer not null, type integer not null
+);
+CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);
+`
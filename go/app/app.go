@@ -67,6 +67,9 @@ func CreatePool(config *pgxpool.Config) (h *controllers.DBHandler) {
 func Init() {
 	h := CreatePool(LoadPoolConfig())
 
+	// keeps the sync change log from growing forever
+	go h.PruneChangeLog(context.Background())
+
 	buildenv := os.Getenv("BUILDENV")
 	log.Println("app: ", buildenv)
 
@@ -435,6 +435,27 @@ func class_setTest() {
 }
 
 func class_userTest() {
+	// get the join code while still signed in as the class's teacher
+	curlTemplate := fmt.Sprintf(
+		`curl -X GET --cookie "cowboy-cards-session=[cookie]" https://cowboy-cards.dsouth.org/api/classes/join_code -sS -H "id: %s"`,
+		extractedClassID,
+	)
+
+	curlOutput, err := executeCurlWithCookieFromString(curlTemplate)
+	if err != nil {
+		fmt.Println("Error:", err)
+		fmt.Println(curlOutput)
+		return
+	}
+
+	re := regexp.MustCompile(`"Code"\s*:\s*"([0-9A-F]+)"`)
+	match := re.FindStringSubmatch(curlOutput)
+	if len(match) < 2 {
+		fmt.Println("join code not found")
+		return
+	}
+	joinCode := match[1]
+
 	// use a temporary user for a controlled test environment
 
 	// curlTemplate := (`curl -i -s -X POST https://cowboy-cards.dsouth.org/signup -H "Content-Type: application/json" -d '{"username":"tempUser","email":"temp@temporary.com","password":"Testing123!", "first_name":"temp", "last_name":"temporary"}'`)
@@ -442,12 +463,12 @@ func class_userTest() {
 	cookie = "MTc0NTg5OTY4NnxEWDhFQVFMX2dBQUJFQUVRQUFCa180QUFBd1p6ZEhKcGJtY01Ed0FOWVhWMGFHVnVkR2xqWVhSbFpBUmliMjlzQWdJQUFRWnpkSEpwYm1jTUNRQUhkWE5sY2w5cFpBVnBiblF6TWdRQ0FGNEdjM1J5YVc1bkRBd0FDbU55WldGMFpXUmZZWFFGYVc1ME5qUUVCZ0Q4MENDaFRBPT18fgeLOgENGTBUvyqzregN4g81QxO4VdKoaJW1HTp9hLk="
 
 	// join class
-	curlTemplate := fmt.Sprintf(
-		`curl -X POST --cookie "cowboy-cards-session=[cookie]" https://cowboy-cards.dsouth.org/api/class_user -sS -H "class_id: %s" -H "role: student"`,
-		extractedClassID,
+	curlTemplate = fmt.Sprintf(
+		`curl -X POST --cookie "cowboy-cards-session=[cookie]" https://cowboy-cards.dsouth.org/api/class_user -sS -H "join_code: %s"`,
+		joinCode,
 	)
 
-	curlOutput, err := executeCurlWithCookieFromString(curlTemplate)
+	curlOutput, err = executeCurlWithCookieFromString(curlTemplate)
 	if err != nil {
 		fmt.Println("Error:", err)
 		fmt.Println(curlOutput)
@@ -864,7 +885,7 @@ func set_userTest() {
 
 	// join set
 	curlTemplate = fmt.Sprintf(
-		`curl -X POST --cookie "cowboy-cards-session=[cookie]" https://cowboy-cards.dsouth.org/api/set_user -sS -H "role:owner" -H "set_id: %s"`,
+		`curl -X POST --cookie "cowboy-cards-session=[cookie]" https://cowboy-cards.dsouth.org/api/set_user -sS -H "set_id: %s"`,
 		tempSetID,
 	)
 
@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/anki"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
+)
+
+const maxApkgBytes int64 = 64 << 20
+
+// the body is the .apkg file. Every note becomes a card in a new set owned by the caller,
+// named after the deck unless set_name is sent. front_field and back_field pick the note
+// fields by name, otherwise the first two are used
+func (h *DBHandler) ImportAnki(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/flashcards/sets/anki -H "front_field: Spanish" -H "back_field: English" --data-binary @deck.apkg
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxApkgBytes))
+	if err != nil {
+		logAndSendError(w, err, fmt.Sprintf("Anki packages are limited to %d MB", maxApkgBytes>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var fields anki.Fields
+	if vals, err := getHeaderVals(r, front_field); err == nil {
+		fields.Front = vals[front_field]
+	}
+	if vals, err := getHeaderVals(r, back_field); err == nil {
+		fields.Back = vals[back_field]
+	}
+
+	deck, err := anki.Read(bytes.NewReader(body), int64(len(body)), fields)
+	if err != nil {
+		if errors.Is(err, anki.ErrNewFormat) {
+			logAndSendError(w, err, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		logAndSendError(w, err, "Invalid Anki package: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// sides over the limit card imports use are skipped like empty ones
+	notes := deck.Notes[:0]
+	for _, note := range deck.Notes {
+		if utf8.RuneCountInString(note.Front) > maxCardSide || utf8.RuneCountInString(note.Back) > maxCardSide {
+			deck.Skipped++
+			continue
+		}
+		notes = append(notes, note)
+	}
+	deck.Notes = notes
+
+	if len(deck.Notes) == 0 {
+		logAndSendError(w, errors.New("no notes"), fmt.Sprintf("No cards with a front and a back of at most %d characters were found", maxCardSide), http.StatusBadRequest)
+		return
+	}
+
+	name := deck.Name
+	if vals, err := getHeaderVals(r, set_name); err == nil {
+		name = vals[set_name]
+	}
+	if strings.TrimSpace(name) == "" {
+		name = "Anki import"
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	err = qtx.SetRevisionAuthor(ctx, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to set revision author", http.StatusInternalServerError)
+		return
+	}
+
+	flashcard_set, err := qtx.CreateFlashcardSet(ctx, db.CreateFlashcardSetParams{
+		SetName:        name,
+		SetDescription: deck.Description,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to create flashcard set", http.StatusInternalServerError)
+		return
+	}
+
+	err = qtx.JoinSet(ctx, db.JoinSetParams{
+		UserID: userID,
+		SetID:  flashcard_set.ID,
+		Role:   owner,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error adding set", http.StatusInternalServerError)
+		return
+	}
+
+	for _, note := range deck.Notes {
+		_, err = qtx.CreateFlashcard(ctx, db.CreateFlashcardParams{
+			Front: note.Front,
+			Back:  note.Back,
+			SetID: flashcard_set.ID,
+		})
+		if err != nil {
+			logAndSendError(w, err, "Failed to create flashcard", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(AnkiImportResponse{
+		Set:      flashcard_set,
+		Imported: len(deck.Notes),
+		Skipped:  deck.Skipped,
+	}); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// any set the caller can read can be exported. With history the caller's own schedule and
+// reviews come along, so they can carry on in Anki where they left off
+func (h *DBHandler) ExportAnki(w http.ResponseWriter, r *http.Request) {
+	// curl localhost:8000/api/flashcards/sets/anki -H "id: 1" -H "history: true" -o set.apkg
+
+	withHistory := false
+	if vals, err := getHeaderVals(r, history); err == nil {
+		withHistory, err = strconv.ParseBool(vals[history])
+		if err != nil {
+			logAndSendError(w, err, "Invalid history", http.StatusBadRequest)
+			return
+		}
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	setID, ok := middleware.GetSetIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	readable, err := canReadSet(ctx, query, r, setID, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to get flashcard set", http.StatusInternalServerError)
+		return
+	} else if !readable {
+		logAndSendError(w, errSetNotFound, "Flashcard set not found", http.StatusNotFound)
+		return
+	}
+
+	flashcard_set, err := query.GetFlashcardSetById(ctx, setID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to get flashcard set", http.StatusInternalServerError)
+		return
+	}
+
+	flashcards, err := query.ListFlashcardsOfASet(ctx, setID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting flashcards from DB", http.StatusInternalServerError)
+		return
+	}
+
+	export := anki.Export{
+		SetID:       setID,
+		Name:        flashcard_set.SetName,
+		Description: flashcard_set.SetDescription,
+		Cards:       make([]anki.Card, len(flashcards)),
+	}
+	for i, card := range flashcards {
+		export.Cards[i] = anki.Card{ID: card.ID, Front: card.Front, Back: card.Back}
+	}
+
+	if withHistory {
+		err = addAnkiHistory(ctx, query, userID, setID, export.Cards)
+		if err != nil {
+			logAndSendError(w, err, "Error getting review history", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// build it first so a failure can still be sent as an error response
+	var buf bytes.Buffer
+	if err := anki.Write(&buf, export); err != nil {
+		logAndSendError(w, err, "Error writing Anki package", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("set-%d.apkg", setID)))
+	w.Write(buf.Bytes())
+}
+
+// addAnkiHistory fills in the user's schedule and reviews for the cards they've studied
+func addAnkiHistory(ctx context.Context, query *db.Queries, userID, setID int32, cards []anki.Card) error {
+	schedules, err := query.ListCardSchedulesInASet(ctx, db.ListCardSchedulesInASetParams{
+		UserID: userID,
+		SetID:  setID,
+	})
+	if err != nil {
+		return err
+	}
+
+	reviews, err := query.ListReviewHistoryInASet(ctx, db.ListReviewHistoryInASetParams{
+		UserID: userID,
+		SetID:  setID,
+	})
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int32]*anki.Card, len(cards))
+	for i := range cards {
+		byID[cards[i].ID] = &cards[i]
+	}
+
+	for _, s := range schedules {
+		if card, ok := byID[s.CardID]; ok {
+			card.Schedule = &anki.Schedule{
+				EaseFactor:   s.EaseFactor,
+				IntervalDays: int(s.IntervalDays),
+				Repetitions:  int(s.Repetitions),
+				Lapses:       int(s.Lapses),
+				DueAt:        s.DueAt.Time,
+			}
+		}
+	}
+
+	// already oldest first
+	for _, review := range reviews {
+		if card, ok := byID[review.CardID]; ok {
+			card.Reviews = append(card.Reviews, anki.Review{
+				Grade:        review.Grade,
+				ResponseMs:   int(review.ResponseMs.Int32),
+				IntervalDays: int(review.IntervalDays),
+				EaseFactor:   review.EaseFactor,
+				ReviewedAt:   review.ReviewedAt.Time,
+			})
+		}
+	}
+
+	return nil
+}
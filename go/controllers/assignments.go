@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func (h *DBHandler) CreateAssignment(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/assignments/ -H "id: 1" -d '{"set_id": 4, "due_at": "2025-05-02T23:59:00-05:00", "required_mastery_pct": 80, "instructions": "chapters 1-3"}'
+
+	var req AssignmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logAndSendError(w, err, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateAssignment(req); err != nil {
+		logAndSendError(w, err, "Invalid assignment", http.StatusBadRequest)
+		return
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	mastery, reviews := assignmentThresholds(req)
+
+	assignment, err := query.CreateAssignment(ctx, db.CreateAssignmentParams{
+		ClassID:            classID,
+		SetID:              req.SetID,
+		RequiredMasteryPct: mastery,
+		MinReviews:         reviews,
+		Instructions:       pgtype.Text{String: req.Instructions, Valid: req.Instructions != ""},
+		DueAt:              pgtype.Timestamptz{Time: req.DueAt, Valid: true},
+	})
+	if err != nil {
+		// the class_set foreign key rejects sets that aren't in the class
+		if strings.Contains(err.Error(), "foreign key") {
+			logAndSendError(w, err, "Set is not in this class", http.StatusBadRequest)
+			return
+		}
+		logAndSendError(w, err, "Failed to create assignment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(assignment); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// the set can't be changed, only the due date, thresholds and instructions
+func (h *DBHandler) UpdateAssignment(w http.ResponseWriter, r *http.Request) {
+	// curl -X PUT localhost:8000/api/assignments/ -H "id: 1" -d '{"id": 2, "due_at": "2025-05-09T23:59:00-05:00", "min_reviews": 50}'
+
+	var req AssignmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logAndSendError(w, err, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ID < 1 {
+		logAndSendError(w, errors.New("invalid assignment id"), "Invalid assignment id", http.StatusBadRequest)
+		return
+	}
+	if err := validateAssignment(req); err != nil {
+		logAndSendError(w, err, "Invalid assignment", http.StatusBadRequest)
+		return
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	mastery, reviews := assignmentThresholds(req)
+
+	assignment, err := query.UpdateAssignment(ctx, db.UpdateAssignmentParams{
+		RequiredMasteryPct: mastery,
+		MinReviews:         reviews,
+		Instructions:       pgtype.Text{String: req.Instructions, Valid: req.Instructions != ""},
+		ID:                 req.ID,
+		ClassID:            classID,
+		DueAt:              pgtype.Timestamptz{Time: req.DueAt, Valid: true},
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			logAndSendError(w, err, "Assignment not found", http.StatusNotFound)
+			return
+		}
+		logAndSendError(w, err, "Failed to update assignment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(assignment); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+func (h *DBHandler) ListAssignmentsInAClass(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/assignments/ -H "id: 1"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	assignments, err := query.ListAssignmentsInAClass(ctx, classID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting assignments", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(assignments); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// every assignment across the user's classes, with progress from card_history. When
+// both thresholds are set both have to be met
+func (h *DBHandler) ListAssignmentsOfAUser(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/assignments/mine
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := query.ListAssignmentsOfAUser(ctx, userID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting assignments", http.StatusInternalServerError)
+		return
+	}
+
+	assignments := make([]AssignmentStatus, len(rows))
+	for i, row := range rows {
+		status := AssignmentStatus{
+			ListAssignmentsOfAUserRow: row,
+			MasteryPct:                masteryPct(row.MasteredCards, row.TotalCards),
+		}
+		status.Complete = assignmentComplete(status.MasteryPct, row.Reviews, row.RequiredMasteryPct, row.MinReviews)
+
+		assignments[i] = status
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(assignments); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+func validateAssignment(req AssignmentRequest) error {
+	if req.DueAt.IsZero() {
+		return errors.New("due_at is required")
+	}
+	if req.RequiredMasteryPct == nil && req.MinReviews == nil {
+		return errors.New("set required_mastery_pct, min_reviews or both")
+	}
+	if req.RequiredMasteryPct != nil && (*req.RequiredMasteryPct < 1 || *req.RequiredMasteryPct > 100) {
+		return errors.New("required_mastery_pct must be between 1 and 100")
+	}
+	if req.MinReviews != nil && *req.MinReviews < 1 {
+		return errors.New("min_reviews must be positive")
+	}
+	return nil
+}
+
+func assignmentThresholds(req AssignmentRequest) (mastery, reviews pgtype.Int4) {
+	if req.RequiredMasteryPct != nil {
+		mastery = pgtype.Int4{Int32: *req.RequiredMasteryPct, Valid: true}
+	}
+	if req.MinReviews != nil {
+		reviews = pgtype.Int4{Int32: *req.MinReviews, Valid: true}
+	}
+	return
+}
+
+func masteryPct(mastered, total int32) int32 {
+	if total == 0 {
+		return 0
+	}
+	return mastered * 100 / total
+}
+
+// an unset threshold doesn't count, a set one has to be met
+func assignmentComplete(masteryPct, reviews int32, requiredMastery, minReviews pgtype.Int4) bool {
+	return (!requiredMastery.Valid || masteryPct >= requiredMastery.Int32) &&
+		(!minReviews.Valid || reviews >= minReviews.Int32)
+}
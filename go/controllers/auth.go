@@ -260,7 +260,16 @@ func generateUniqueToken() (string, error) {
 }
 
 func SendEmail(w http.ResponseWriter, to, subject, body string) error {
-	fmt.Println(os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), os.Getenv("SMTP_HOST"), os.Getenv("SMTP_PORT"))
+	if err := sendMail(to, subject, body); err != nil {
+		logAndSendError(w, err, "Error sending email", http.StatusBadRequest)
+		return err
+	}
+	return nil
+}
+
+// sendMail does the sending for SendEmail; batch handlers call it directly so one
+// failed address doesn't write an error response
+func sendMail(to, subject, body string) error {
 	from := os.Getenv("SMTP_USERNAME")
 	password := os.Getenv("SMTP_PASSWORD")
 	smtpHost := os.Getenv("SMTP_HOST")
@@ -270,12 +279,7 @@ func SendEmail(w http.ResponseWriter, to, subject, body string) error {
 
 	message := fmt.Appendf(nil, "To: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n", to, subject, body)
 
-	err := smtp.SendMail(smtpHost+":"+smtpPort, auth, from, []string{to}, message)
-	if err != nil {
-		logAndSendError(w, err, "Error sending email", http.StatusBadRequest)
-		return err
-	}
-	return nil
+	return smtp.SendMail(smtpHost+":"+smtpPort, auth, from, []string{to}, message)
 }
 
 func CheckPasswordStrength(password string) error {
@@ -1,12 +1,33 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"path"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/grading"
 	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/scheduler"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	maxBatchReviews int   = 500
+	maxTypedAnswer  int   = 500 // runes
+	maxReviewBytes  int64 = 16 << 10
+	maxBatchBytes   int64 = 2 << 20 // room for maxBatchReviews reviews with long answers
+
+	maxOfflineReviewAge = 30 * 24 * time.Hour // oldest reviewed_at a batch may carry
 )
 
 func (h *DBHandler) UpdateFlashcardScore(w http.ResponseWriter, r *http.Request) {
@@ -38,32 +59,320 @@ func (h *DBHandler) UpdateFlashcardScore(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	readable, err := canReadCard(ctx, query, r, cardID, userID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting flashcard", http.StatusInternalServerError)
+		return
+	} else if !readable {
+		logAndSendError(w, errSetNotFound, "Flashcard not found", http.StatusNotFound)
+		return
+	}
+
+	review := ReviewRequest{CardID: cardID}
 	switch path.Base(r.URL.Path) {
 	case correct:
-		err = query.UpsertCorrectFlashcardScore(ctx, db.UpsertCorrectFlashcardScoreParams{
-			UserID: userID,
-			CardID: cardID,
-		})
+		review.Grade = scheduler.Good.String()
 	case incorrect:
-		err = query.UpsertIncorrectFlashcardScore(ctx, db.UpsertIncorrectFlashcardScoreParams{
-			UserID: userID,
-			CardID: cardID,
-		})
+		review.Grade = scheduler.Again.String()
 	default:
 		logAndSendError(w, errHeader, "Improper header", http.StatusBadRequest)
 		return
 	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	err = reviewCard(ctx, qtx, userID, review)
 	if err != nil {
 		logAndSendError(w, err, "Error updating score", http.StatusInternalServerError)
 		return
 	}
 
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusCreated) // TODO - WriteHeader insert case only, not update case
 	if err = json.NewEncoder(w).Encode("Score updated"); err != nil {
 		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
 	}
 }
 
+func (h *DBHandler) ReviewFlashcard(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/card_history/review -d '{"card_id": 1, "grade": "hard", "response_ms": 5200, "answer": "hola"}'
+
+	var req ReviewRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxReviewBytes)).Decode(&req); err != nil {
+		logAndSendError(w, err, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateReview(req); err != nil {
+		logAndSendError(w, err, "Invalid review", http.StatusBadRequest)
+		return
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	readable, err := canReadCard(ctx, query, r, req.CardID, userID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting flashcard", http.StatusInternalServerError)
+		return
+	} else if !readable {
+		logAndSendError(w, errSetNotFound, "Flashcard not found", http.StatusNotFound)
+		return
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	err = reviewCard(ctx, qtx, userID, req)
+	if err != nil {
+		logAndSendError(w, err, "Error recording review", http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode("Review recorded"); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// the student types the back and the server decides the grade: an exact match (after
+// normalizing) is good, one within the set's typo tolerance is hard, anything else is again
+func (h *DBHandler) ReviewTypedAnswer(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/card_history/typed -d '{"card_id": 1, "answer": "la biblioteca", "response_ms": 4100}'
+
+	var req TypedAnswerRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxReviewBytes)).Decode(&req); err != nil {
+		logAndSendError(w, err, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.CardID < 1 {
+		logAndSendError(w, errors.New("invalid card id"), "Invalid card id", http.StatusBadRequest)
+		return
+	}
+	if req.ResponseMs == nil || *req.ResponseMs < 0 {
+		logAndSendError(w, errors.New("invalid response_ms"), "response_ms must be a non-negative number of milliseconds", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(req.Answer) > maxTypedAnswer {
+		logAndSendError(w, errors.New("answer too long"), fmt.Sprintf("Answers are limited to %d characters", maxTypedAnswer), http.StatusBadRequest)
+		return
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	card, err := query.GetCardForTypedAnswer(ctx, req.CardID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			logAndSendError(w, err, "Flashcard not found", http.StatusNotFound)
+			return
+		}
+		logAndSendError(w, err, "Error getting flashcard", http.StatusInternalServerError)
+		return
+	}
+
+	// the result includes the expected answer
+	readable, err := canReadSet(ctx, query, r, card.SetID, userID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting flashcard", http.StatusInternalServerError)
+		return
+	} else if !readable {
+		logAndSendError(w, errSetNotFound, "Flashcard not found", http.StatusNotFound)
+		return
+	}
+
+	res := TypedAnswerResult{
+		Result:   grading.Check(card.Back, req.Answer, int(card.TypoTolerance)),
+		Expected: card.Back,
+	}
+
+	grade := scheduler.Again
+	switch {
+	case res.Exact:
+		grade = scheduler.Good
+	case res.Correct:
+		grade = scheduler.Hard
+	}
+	res.Grade = grade.String()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	err = reviewCard(ctx, qtx, userID, ReviewRequest{
+		CardID:     req.CardID,
+		Grade:      res.Grade,
+		ResponseMs: req.ResponseMs,
+		Answer:     req.Answer,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error recording review", http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+func (h *DBHandler) ReviewFlashcardBatch(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/card_history/batch -d '{"reviews": [{"client_id": "a1", "card_id": 1, "grade": "good", "response_ms": 900, "reviewed_at": "2025-04-01T08:30:00Z"}]}'
+
+	var req BatchReviewRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBytes)).Decode(&req); err != nil {
+		logAndSendError(w, err, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Reviews) == 0 || len(req.Reviews) > maxBatchReviews {
+		logAndSendError(w, errors.New("batch size"), fmt.Sprintf("Send between 1 and %d reviews", maxBatchReviews), http.StatusBadRequest)
+		return
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// apply oldest first so the schedule replays in the order the student studied,
+	// but report back in the order the app sent them
+	now := time.Now()
+	order := make([]int, len(req.Reviews))
+	for i := range req.Reviews {
+		order[i] = i
+		if req.Reviews[i].ReviewedAt == nil || req.Reviews[i].ReviewedAt.After(now) {
+			req.Reviews[i].ReviewedAt = &now
+		}
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return req.Reviews[a].ReviewedAt.Compare(*req.Reviews[b].ReviewedAt)
+	})
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	results := make([]ReviewResult, len(req.Reviews))
+	for _, i := range order {
+		review := req.Reviews[i]
+		results[i] = ReviewResult{ClientID: review.ClientID}
+
+		if review.ClientID == "" {
+			results[i].Status, results[i].Error = statusError, "client_id is required"
+			continue
+		}
+		if err := validateReview(review); err != nil {
+			results[i].Status, results[i].Error = statusError, err.Error()
+			continue
+		}
+		if review.ReviewedAt.Before(now.Add(-maxOfflineReviewAge)) {
+			results[i].Status, results[i].Error = statusError, "reviewed_at is too far in the past"
+			continue
+		}
+
+		readable, err := canReadCard(ctx, query, r, review.CardID, userID)
+		if err != nil {
+			log.Printf("batch review %s: %v", review.ClientID, err)
+			results[i].Status, results[i].Error = statusError, "Error getting flashcard"
+			continue
+		} else if !readable {
+			results[i].Status, results[i].Error = statusForbidden, "Flashcard not found"
+			continue
+		}
+
+		status, err := applyBatchReview(ctx, tx, query, userID, review)
+		if err != nil {
+			log.Printf("batch review %s: %v", review.ClientID, err)
+			results[i].Status, results[i].Error = statusError, "Error recording review"
+			continue
+		}
+		results[i].Status = status
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(results); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
 func (h *DBHandler) GetCardScore(w http.ResponseWriter, r *http.Request) {
 	// curl -X GET localhost:8000/api/card_history/ -H "card_id: 1"
 	query, ctx, conn, err := getQueryConnAndContext(r, h)
@@ -150,3 +459,276 @@ func (h *DBHandler) GetScoresInASet(w http.ResponseWriter, r *http.Request) {
 		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
 	}
 }
+
+func (h *DBHandler) ListDueCards(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/card_history/due
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	cards, err := query.ListDueCards(ctx, userID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting due cards", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(cards); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+func (h *DBHandler) ListMasteryCounts(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/card_history/mastery
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	counts, err := query.ListMasteryCountsOfAUser(ctx, userID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting mastery counts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(counts); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+func (h *DBHandler) GetMasteryCountsInASet(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/card_history/mastery/set -H "set_id: 1"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, set_id)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	setID, err := getInt32Id(headerVals[set_id])
+	if err != nil {
+		logAndSendError(w, err, "Invalid set id", http.StatusBadRequest)
+		return
+	}
+
+	counts, err := query.GetMasteryCountsInASet(ctx, db.GetMasteryCountsInASetParams{
+		UserID: userID,
+		SetID:  setID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error getting mastery counts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(counts); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// canReadCard checks the user can read the card's set; reviewing a card shows its sides back
+// in the review log. A card that doesn't exist can't be read
+func canReadCard(ctx context.Context, query *db.Queries, r *http.Request, cardID, userID int32) (bool, error) {
+	card, err := query.GetFlashcardById(ctx, cardID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return canReadSet(ctx, query, r, card.SetID, userID)
+}
+
+// applyBatchReview runs one review of a batch inside its own savepoint so a bad card id
+// only fails that review. A client id already in review_log is reported as a duplicate
+// and skipped, which keeps resent batches from bumping set_score twice
+func applyBatchReview(ctx context.Context, tx pgx.Tx, query *db.Queries, userID int32, review ReviewRequest) (status string, err error) {
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer sp.Rollback(ctx)
+
+	qsp := query.WithTx(sp)
+
+	logged, err := qsp.IsReviewLogged(ctx, db.IsReviewLoggedParams{
+		UserID:        userID,
+		ClientEventID: pgtype.Text{String: review.ClientID, Valid: true},
+	})
+	if err != nil {
+		return "", err
+	}
+	if logged {
+		return statusDuplicate, nil
+	}
+
+	if err = reviewCard(ctx, qsp, userID, review); err != nil {
+		return "", err
+	}
+
+	if err = sp.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return statusApplied, nil
+}
+
+func validateReview(review ReviewRequest) error {
+	if review.CardID < 1 {
+		return errors.New("invalid card id")
+	}
+	if review.ResponseMs == nil || *review.ResponseMs < 0 {
+		return errors.New("response_ms must be a non-negative number of milliseconds")
+	}
+	if utf8.RuneCountInString(review.Answer) > maxTypedAnswer {
+		return fmt.Errorf("answers are limited to %d characters", maxTypedAnswer)
+	}
+	_, err := scheduler.ParseGrade(review.Grade)
+	return err
+}
+
+// reviewCard records one graded answer: it bumps the score counters (and, through
+// the update_set_score trigger, set_score), reschedules the card and appends to review_log.
+// query should be bound to a tx so the writes land together
+func reviewCard(ctx context.Context, query *db.Queries, userID int32, review ReviewRequest) (err error) {
+	grade, err := scheduler.ParseGrade(review.Grade)
+	if err != nil {
+		return err
+	}
+
+	var reviewedAt pgtype.Timestamptz
+	if review.ReviewedAt != nil {
+		reviewedAt = pgtype.Timestamptz{Time: *review.ReviewedAt, Valid: true}
+	}
+
+	state := scheduler.New()
+
+	current, err := query.GetCardSchedule(ctx, db.GetCardScheduleParams{
+		ReviewedAt: reviewedAt,
+		UserID:     userID,
+		CardID:     review.CardID,
+	})
+	if err == nil {
+		state = scheduler.State{
+			EaseFactor:    current.EaseFactor,
+			IntervalDays:  current.IntervalDays,
+			Repetitions:   current.Repetitions,
+			Lapses:        current.Lapses,
+			CorrectStreak: current.CorrectStreak,
+		}
+	} else if !strings.Contains(err.Error(), "no rows") {
+		return err
+	}
+
+	if grade.Passed() {
+		err = query.UpsertCorrectFlashcardScore(ctx, db.UpsertCorrectFlashcardScoreParams{
+			UserID: userID,
+			CardID: review.CardID,
+		})
+	} else {
+		err = query.UpsertIncorrectFlashcardScore(ctx, db.UpsertIncorrectFlashcardScoreParams{
+			UserID: userID,
+			CardID: review.CardID,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	next, dueIn := scheduler.Next(state, grade)
+
+	rule, err := query.GetMasteryRule(ctx, db.GetMasteryRuleParams{
+		DefaultStreak:       scheduler.DefaultMastery.Streak,
+		DefaultIntervalDays: scheduler.DefaultMastery.IntervalDays,
+		UserID:              userID,
+		CardID:              review.CardID,
+	})
+	if err != nil {
+		return err
+	}
+
+	mastered := scheduler.MasteryRule{
+		Streak:       rule.Streak,
+		IntervalDays: rule.IntervalDays,
+	}.Mastered(next)
+
+	var responseMs pgtype.Int4
+	if review.ResponseMs != nil {
+		responseMs = pgtype.Int4{Int32: *review.ResponseMs, Valid: true}
+	}
+	answer := pgtype.Text{String: review.Answer, Valid: review.Answer != ""}
+
+	// an offline review older than the card's last review would rewind the schedule,
+	// so it only counts towards the score and the log
+	if current.Stale {
+		next = state
+	} else {
+		err = query.UpdateCardSchedule(ctx, db.UpdateCardScheduleParams{
+			EaseFactor:     next.EaseFactor,
+			IntervalDays:   next.IntervalDays,
+			Repetitions:    next.Repetitions,
+			Lapses:         next.Lapses,
+			CorrectStreak:  next.CorrectStreak,
+			IsMastered:     mastered,
+			LastGrade:      pgtype.Text{String: grade.String(), Valid: true},
+			LastResponseMs: responseMs,
+			LastAnswer:     answer,
+			UserID:         userID,
+			CardID:         review.CardID,
+			ReviewedAt:     reviewedAt,
+			DueInMinutes:   int32(dueIn.Minutes()),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return query.LogReview(ctx, db.LogReviewParams{
+		UserID:        userID,
+		CardID:        review.CardID,
+		Grade:         grade.String(),
+		ResponseMs:    responseMs,
+		Answer:        answer,
+		IntervalDays:  next.IntervalDays,
+		EaseFactor:    next.EaseFactor,
+		ClientEventID: pgtype.Text{String: review.ClientID, Valid: review.ClientID != ""},
+		ReviewedAt:    reviewedAt,
+	})
+}
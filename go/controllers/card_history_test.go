@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateReview(t *testing.T) {
+	ms := int32(900)
+	negative := int32(-1)
+
+	tests := []struct {
+		name    string
+		review  ReviewRequest
+		wantErr string
+	}{
+		{"valid", ReviewRequest{CardID: 1, Grade: "good", ResponseMs: &ms, Answer: "hola"}, ""},
+		{"no card", ReviewRequest{Grade: "good", ResponseMs: &ms}, "invalid card id"},
+		{"no response time", ReviewRequest{CardID: 1, Grade: "good"}, "response_ms"},
+		{"negative response time", ReviewRequest{CardID: 1, Grade: "good", ResponseMs: &negative}, "response_ms"},
+		{"long answer", ReviewRequest{CardID: 1, Grade: "good", ResponseMs: &ms, Answer: strings.Repeat("é", maxTypedAnswer+1)}, "answers are limited"},
+		{"bad grade", ReviewRequest{CardID: 1, Grade: "great", ResponseMs: &ms}, "grade"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateReview(tt.review)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("got %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
+)
+
+const (
+	maxImportCards int   = 2000
+	maxImportBytes int64 = 2 << 20
+	maxCardSide    int   = 5000 // runes
+
+	formatTSV  string = "tsv"
+	formatText string = "text"
+)
+
+// one parsed row of an import, before it's checked
+type importRow struct {
+	line  int
+	front string
+	back  string
+	err   string
+}
+
+// the body is csv, tsv or pasted "term<sep>definition" text, one card per row. Rows with
+// problems and duplicates (of another row or a card already in the set) are reported and
+// skipped, everything else goes in in one transaction. With dry_run nothing is written and
+// the response is a preview of what would be imported
+func (h *DBHandler) ImportFlashcards(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/flashcards/import -H "set_id: 1" -H "format: text" -H "separator: tab" -H "card_separator: newline" -H "dry_run: true" --data-binary @cards.txt
+
+	importFormat, err := getReportFormat(r, formatText, formatCSV, formatTSV)
+	if err != nil {
+		logAndSendError(w, err, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	termSep, cardSep, err := getImportSeparators(r, importFormat)
+	if err != nil {
+		logAndSendError(w, err, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dryRun := false
+	if vals, err := getHeaderVals(r, dry_run); err == nil {
+		dryRun, err = strconv.ParseBool(vals[dry_run])
+		if err != nil {
+			logAndSendError(w, err, "Invalid dry_run", http.StatusBadRequest)
+			return
+		}
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	setID, ok := middleware.GetSetIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != owner {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// only owners get as far as reading the body
+	var rows []importRow
+	body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+	if importFormat == formatText {
+		rows, err = readImportText(body, termSep, cardSep)
+	} else {
+		rows, err = readImportCSV(body, []rune(termSep)[0])
+	}
+	if err != nil {
+		logAndSendError(w, err, "Invalid import: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	existing, err := query.ListFlashcardsOfASet(ctx, setID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting flashcards from DB", http.StatusInternalServerError)
+		return
+	}
+
+	seen := make(map[string]bool, len(existing)+len(rows))
+	for _, card := range existing {
+		seen[cardKey(card.Front, card.Back)] = true
+	}
+
+	res := CardImportResponse{
+		DryRun: dryRun,
+		Rows:   make([]CardImportResult, len(rows)),
+	}
+
+	var toCreate []int
+	for i, row := range rows {
+		res.Rows[i] = CardImportResult{Row: row.line, Front: row.front, Back: row.back}
+
+		key := cardKey(row.front, row.back)
+		switch {
+		case row.err != "":
+			res.Rows[i].Status, res.Rows[i].Detail = statusError, row.err
+		case seen[key]:
+			res.Rows[i].Status, res.Rows[i].Detail = statusDuplicate, "same term and definition as another card"
+		default:
+			seen[key] = true
+			res.Rows[i].Status = statusCreated
+			toCreate = append(toCreate, i)
+		}
+	}
+	res.Imported = len(toCreate)
+
+	if !dryRun && len(toCreate) > 0 {
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback(ctx)
+
+		qtx := query.WithTx(tx)
+
+		err = qtx.SetRevisionAuthor(ctx, userID)
+		if err != nil {
+			logAndSendError(w, err, "Failed to set revision author", http.StatusInternalServerError)
+			return
+		}
+
+		for _, i := range toCreate {
+			card, err := qtx.CreateFlashcard(ctx, db.CreateFlashcardParams{
+				Front: rows[i].front,
+				Back:  rows[i].back,
+				SetID: setID,
+			})
+			if err != nil {
+				logAndSendError(w, err, fmt.Sprintf("Failed to create flashcard from row %d", rows[i].line), http.StatusInternalServerError)
+				return
+			}
+			res.Rows[i].CardID = card.ID
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !dryRun {
+		w.WriteHeader(http.StatusCreated)
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// getImportSeparators reads the separator (between term and definition) and card_separator
+// (between cards, pasted text only) headers. Both take a name like tab or newline, since
+// those can't be sent in a header, or the literal text to split on
+func getImportSeparators(r *http.Request, importFormat string) (termSep, cardSep string, err error) {
+	switch importFormat {
+	case formatCSV:
+		termSep = ","
+	default:
+		termSep = "\t"
+	}
+	cardSep = "\n"
+
+	if vals, err := getHeaderVals(r, separator); err == nil {
+		termSep = namedSeparator(vals[separator])
+	}
+	if vals, err := getHeaderVals(r, card_separator); err == nil {
+		if importFormat != formatText {
+			return "", "", errors.New("card_separator only applies to the text format, csv and tsv use one card per line")
+		}
+		cardSep = namedSeparator(vals[card_separator])
+	}
+
+	if importFormat != formatText {
+		// encoding/csv splits on a single character that can't be a quote or line break
+		if utf8.RuneCountInString(termSep) != 1 || strings.ContainsAny(termSep, "\"\r\n") {
+			return "", "", errors.New("csv and tsv separators must be a single character other than a quote or line break")
+		}
+	} else if termSep == cardSep {
+		return "", "", errors.New("separator and card_separator must be different")
+	}
+	return termSep, cardSep, nil
+}
+
+func namedSeparator(val string) string {
+	switch strings.ToLower(val) {
+	case "tab":
+		return "\t"
+	case "comma":
+		return ","
+	case "semicolon":
+		return ";"
+	case "newline":
+		return "\n"
+	case "dash":
+		return " - "
+	}
+	return val
+}
+
+// readImportText splits pasted text into cards on cardSep and each card on the first termSep,
+// so a definition can contain the separator. Blank cards are ignored
+func readImportText(body io.Reader, termSep, cardSep string) ([]importRow, error) {
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.ReplaceAll(string(raw), "\r\n", "\n")
+
+	var rows []importRow
+	for i, card := range strings.Split(text, cardSep) {
+		if strings.TrimSpace(card) == "" {
+			continue
+		}
+
+		row := importRow{line: i + 1}
+		if term, definition, ok := strings.Cut(card, termSep); ok {
+			row = newImportRow(row.line, []string{term, definition})
+		} else {
+			row.front = strings.TrimSpace(card)
+			row.err = "no separator between term and definition"
+		}
+
+		rows = append(rows, row)
+		if len(rows) > maxImportCards {
+			return nil, fmt.Errorf("more than %d cards", maxImportCards)
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("no cards found")
+	}
+	return rows, nil
+}
+
+// readImportCSV reads csv or tsv with an optional front,back or term,definition header row.
+// Quoting errors reject the whole file, anything wrong with a single row is reported on it
+func readImportCSV(body io.Reader, comma rune) ([]importRow, error) {
+	reader := csv.NewReader(body)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = comma == '\t'
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		for i, field := range record {
+			record[i] = unescapeCSVCell(field)
+		}
+
+		if len(rows) == 0 && len(record) == 2 && isImportHeader(record[0], record[1]) {
+			continue
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		rows = append(rows, newImportRow(line, record))
+		if len(rows) > maxImportCards {
+			return nil, fmt.Errorf("more than %d cards", maxImportCards)
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("no cards found")
+	}
+	return rows, nil
+}
+
+func newImportRow(line int, fields []string) importRow {
+	row := importRow{line: line}
+	if len(fields) > 0 {
+		row.front = strings.TrimSpace(fields[0])
+	}
+	if len(fields) > 1 {
+		row.back = strings.TrimSpace(fields[1])
+	}
+
+	switch {
+	case len(fields) != 2:
+		row.err = fmt.Sprintf("expected a term and a definition, got %d fields", len(fields))
+	case row.front == "":
+		row.err = "term is empty"
+	case row.back == "":
+		row.err = "definition is empty"
+	case utf8.RuneCountInString(row.front) > maxCardSide || utf8.RuneCountInString(row.back) > maxCardSide:
+		row.err = fmt.Sprintf("terms and definitions are limited to %d characters", maxCardSide)
+	}
+	return row
+}
+
+func isImportHeader(first, second string) bool {
+	first, second = strings.ToLower(strings.TrimSpace(first)), strings.ToLower(strings.TrimSpace(second))
+	return (first == front && second == back) || (first == "term" && second == "definition")
+}
+
+// cards count as duplicates when both sides match ignoring case and spacing
+func cardKey(front, back string) string {
+	return strings.ToLower(strings.Join(strings.Fields(front), " ")) + "\x00" + strings.ToLower(strings.Join(strings.Fields(back), " "))
+}
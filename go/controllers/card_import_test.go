@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadImportText(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		termSep string
+		cardSep string
+		want    []importRow
+		wantErr bool
+	}{
+		{
+			name:    "tab and newline",
+			body:    "hola\thello\r\nadiós\tgoodbye\n",
+			termSep: "\t",
+			cardSep: "\n",
+			want: []importRow{
+				{line: 1, front: "hola", back: "hello"},
+				{line: 2, front: "adiós", back: "goodbye"},
+			},
+		},
+		{
+			name:    "definition keeps later separators",
+			body:    "to be - ser - estar",
+			termSep: " - ",
+			cardSep: "\n",
+			want:    []importRow{{line: 1, front: "to be", back: "ser - estar"}},
+		},
+		{
+			name:    "blank cards are skipped but keep line numbers",
+			body:    "a,1;;  ;b,2",
+			termSep: ",",
+			cardSep: ";",
+			want: []importRow{
+				{line: 1, front: "a", back: "1"},
+				{line: 4, front: "b", back: "2"},
+			},
+		},
+		{
+			name:    "no separator is reported on the row",
+			body:    "just a term",
+			termSep: "\t",
+			cardSep: "\n",
+			want:    []importRow{{line: 1, front: "just a term", err: "no separator between term and definition"}},
+		},
+		{
+			name:    "empty",
+			body:    "\n \n",
+			termSep: "\t",
+			cardSep: "\n",
+			wantErr: true,
+		},
+		{
+			name:    "too many cards",
+			body:    strings.Repeat("a\tb\n", maxImportCards+1),
+			termSep: "\t",
+			cardSep: "\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readImportText(strings.NewReader(tt.body), tt.termSep, tt.cardSep)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadImportCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		comma   rune
+		want    []importRow
+		wantErr bool
+	}{
+		{
+			name:  "header row is skipped",
+			body:  "Front,Back\nhola,hello\n\"a, b\",\"c\nd\"\n",
+			comma: ',',
+			want: []importRow{
+				{line: 2, front: "hola", back: "hello"},
+				{line: 3, front: "a, b", back: "c\nd"},
+			},
+		},
+		{
+			name:  "term definition header and tsv",
+			body:  "term\tdefinition\nel \"gato\"\tthe cat\n",
+			comma: '\t',
+			want:  []importRow{{line: 2, front: "el \"gato\"", back: "the cat"}},
+		},
+		{
+			name:  "exported formula cells come back unchanged",
+			body:  "front,back\n'=1+1,'-ar verbs\n",
+			comma: ',',
+			want:  []importRow{{line: 2, front: "=1+1", back: "-ar verbs"}},
+		},
+		{
+			name:  "bad rows are reported on the row",
+			body:  "a,b,c\n,b\n",
+			comma: ',',
+			want: []importRow{
+				{line: 1, front: "a", back: "b", err: "expected a term and a definition, got 3 fields"},
+				{line: 2, back: "b", err: "term is empty"},
+			},
+		},
+		{
+			name:    "bad quoting rejects the file",
+			body:    "\"a,b\n",
+			comma:   ',',
+			wantErr: true,
+		},
+		{
+			name:    "only a header",
+			body:    "front,back\n",
+			comma:   ',',
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readImportCSV(strings.NewReader(tt.body), tt.comma)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewImportRow(t *testing.T) {
+	long := strings.Repeat("é", maxCardSide+1)
+
+	tests := []struct {
+		name   string
+		fields []string
+		want   importRow
+	}{
+		{"trimmed", []string{"  hola ", "\thello "}, importRow{line: 3, front: "hola", back: "hello"}},
+		{"one field", []string{"hola"}, importRow{line: 3, front: "hola", err: "expected a term and a definition, got 1 fields"}},
+		{"empty term", []string{" ", "hello"}, importRow{line: 3, back: "hello", err: "term is empty"}},
+		{"empty definition", []string{"hola", ""}, importRow{line: 3, front: "hola", err: "definition is empty"}},
+		{"too long", []string{"hola", long}, importRow{line: 3, front: "hola", back: long, err: "terms and definitions are limited to 5000 characters"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newImportRow(3, tt.fields); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCardKey(t *testing.T) {
+	tests := []struct {
+		a, b [2]string
+		same bool
+	}{
+		{[2]string{"Hola", "Hello"}, [2]string{"hola", "hello"}, true},
+		{[2]string{"buenos  días", " good\tmorning "}, [2]string{"Buenos días", "good morning"}, true},
+		{[2]string{"hola", "hello"}, [2]string{"hola", "hi"}, false},
+		// the separator keeps sides from running into each other
+		{[2]string{"ab", "c"}, [2]string{"a", "bc"}, false},
+	}
+
+	for _, tt := range tests {
+		got := cardKey(tt.a[0], tt.a[1]) == cardKey(tt.b[0], tt.b[1])
+		if got != tt.same {
+			t.Errorf("cardKey(%q) == cardKey(%q) is %v, want %v", tt.a, tt.b, got, tt.same)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	maxInviteHours int = 30 * 24
+
+	// random codes and tokens are drawn again when they collide with one already in use
+	maxCodeAttempts int = 5
+)
+
+func (h *DBHandler) GetJoinCode(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/classes/join_code -H "id: 1"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	code, err := query.GetJoinCode(ctx, classID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			logAndSendError(w, err, "Class has no join code yet", http.StatusNotFound)
+			return
+		}
+		logAndSendError(w, err, "Error getting join code", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(code); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// a new code invalidates the old one; the body is optional and only expires_at is used
+func (h *DBHandler) RegenerateJoinCode(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/classes/join_code -H "id: 1" -d '{"expires_at": "2025-06-01T00:00:00-05:00"}'
+
+	var req JoinCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		logAndSendError(w, err, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	code, err := regenerateJoinCode(ctx, tx, query, db.RegenerateJoinCodeParams{
+		ClassID:   classID,
+		ExpiresAt: joinCodeExpiry(req),
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to regenerate join code", http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(code); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// enables/disables the code or changes its expiry without changing the code itself
+func (h *DBHandler) UpdateJoinCode(w http.ResponseWriter, r *http.Request) {
+	// curl -X PUT localhost:8000/api/classes/join_code -H "id: 1" -d '{"enabled": false}'
+
+	var req JoinCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logAndSendError(w, err, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Enabled == nil {
+		logAndSendError(w, errors.New("enabled is required"), "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	code, err := query.UpdateJoinCode(ctx, db.UpdateJoinCodeParams{
+		Enabled:   *req.Enabled,
+		ClassID:   classID,
+		ExpiresAt: joinCodeExpiry(req),
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			logAndSendError(w, err, "Class has no join code yet", http.StatusNotFound)
+			return
+		}
+		logAndSendError(w, err, "Failed to update join code", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(code); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// lists invites that haven't expired yet
+func (h *DBHandler) ListClassInvites(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/classes/invites -H "id: 1"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	invites, err := query.ListClassInvites(ctx, classID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting invites", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(invites); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// the token goes in the invite link and is sent back as the token header of JoinClass
+func (h *DBHandler) CreateClassInvite(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/classes/invites -H "id: 1" -H "expires_in_hours: 72"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, expires_in_hours)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	hours, err := strconv.Atoi(headerVals[expires_in_hours])
+	if err != nil || hours < 1 || hours > maxInviteHours {
+		logAndSendError(w, errHeader, fmt.Sprintf("Invites expire after 1 to %d hours", maxInviteHours), http.StatusBadRequest)
+		return
+	}
+
+	var invite db.ClassInvite
+	for attempt := 1; ; attempt++ {
+		inviteToken, err := generateUniqueToken()
+		if err != nil {
+			logAndSendError(w, err, "Failed to generate invite", http.StatusInternalServerError)
+			return
+		}
+
+		invite, err = query.CreateClassInvite(ctx, db.CreateClassInviteParams{
+			Token:   inviteToken,
+			ClassID: classID,
+			Hours:   int32(hours),
+		})
+		if err == nil {
+			break
+		} else if !strings.Contains(err.Error(), "duplicate key") || attempt == maxCodeAttempts {
+			logAndSendError(w, err, "Failed to create invite", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(invite); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+func (h *DBHandler) DeleteClassInvite(w http.ResponseWriter, r *http.Request) {
+	// curl -X DELETE localhost:8000/api/classes/invites -H "id: 1" -H "token: invite-token"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, token)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	err = query.DeleteClassInvite(ctx, db.DeleteClassInviteParams{
+		Token:   headerVals[token],
+		ClassID: classID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to delete invite", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	w.Write([]byte{})
+}
+
+// regenerateJoinCode draws a new code for the class, drawing again if it's already another
+// class's. Each try runs in a savepoint so a collision doesn't abort the caller's tx
+func regenerateJoinCode(ctx context.Context, tx pgx.Tx, query *db.Queries, params db.RegenerateJoinCodeParams) (db.ClassJoinCode, error) {
+	for attempt := 1; ; attempt++ {
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			return db.ClassJoinCode{}, err
+		}
+
+		code, err := query.WithTx(sp).RegenerateJoinCode(ctx, params)
+		if err == nil {
+			return code, sp.Commit(ctx)
+		}
+		sp.Rollback(ctx)
+
+		if !strings.Contains(err.Error(), "duplicate key") || attempt == maxCodeAttempts {
+			return db.ClassJoinCode{}, err
+		}
+	}
+}
+
+func joinCodeExpiry(req JoinCodeRequest) pgtype.Timestamptz {
+	if req.ExpiresAt == nil {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: *req.ExpiresAt, Valid: true}
+}
@@ -42,6 +42,23 @@ func (h *DBHandler) AddSetToClass(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// the class can read whatever is added to it, so the teacher has to be able to read it first
+	readable, err := canReadSet(ctx, query, r, setID, userID)
+	if err != nil {
+		logAndSendError(w, err, "Error adding set", http.StatusInternalServerError)
+		return
+	} else if !readable {
+		logAndSendError(w, errSetNotFound, "Flashcard set not found", http.StatusNotFound)
+		return
+	}
+
 	err = query.AddSetToClass(ctx, db.AddSetToClassParams{
 		ClassID: classID,
 		SetID:   setID,
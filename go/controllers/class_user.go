@@ -1,16 +1,20 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
 	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
 )
 
+// students only ever join through a class's join code or an invite link
 func (h *DBHandler) JoinClass(w http.ResponseWriter, r *http.Request) {
-	//curl -X POST localhost:8000/api/class_user -H "class_id: 1" -H "role: Student"
+	//curl -X POST localhost:8000/api/class_user -H "join_code: 3FA85F645717"
+	//curl -X POST localhost:8000/api/class_user -H "token: invite-token"
 
 	query, ctx, conn, err := getQueryConnAndContext(r, h)
 	if err != nil {
@@ -26,34 +30,228 @@ func (h *DBHandler) JoinClass(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	headerVals, err := getHeaderVals(r, class_id, roleStr)
-	if err != nil {
-		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+	var classID int32
+	if headerVals, headerErr := getHeaderVals(r, join_code); headerErr == nil {
+		classID, err = query.GetClassIdByJoinCode(ctx, headerVals[join_code])
+	} else if headerVals, headerErr := getHeaderVals(r, token); headerErr == nil {
+		classID, err = query.GetClassIdByInvite(ctx, headerVals[token])
+	} else {
+		logAndSendError(w, headerErr, "Header error", http.StatusBadRequest)
 		return
 	}
-
-	classID, err := getInt32Id(headerVals[class_id])
 	if err != nil {
-		logAndSendError(w, err, "Invalid class id", http.StatusBadRequest)
+		if strings.Contains(err.Error(), "no rows") {
+			logAndSendError(w, err, "Invalid or expired join code", http.StatusNotFound)
+			return
+		}
+		logAndSendError(w, err, "Failed to join class", http.StatusInternalServerError)
 		return
 	}
 
 	err = query.JoinClass(ctx, db.JoinClassParams{
 		UserID:  userID,
 		ClassID: classID,
-		Role:    headerVals[roleStr],
+		Role:    student,
 	})
 	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			logAndSendError(w, err, "Already a member of this class", http.StatusConflict)
+			return
+		}
 		logAndSendError(w, err, "Failed to join class", http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode("Class joined"); err != nil {
+	if err := json.NewEncoder(w).Encode(classID); err != nil {
 		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
 	}
 }
 
+// promotes a student to co-teacher or demotes a teacher, as long as one teacher is left
+func (h *DBHandler) UpdateClassMemberRole(w http.ResponseWriter, r *http.Request) {
+	// curl -X PUT localhost:8000/api/classes/role -H "id: 1" -H "student_id: 5" -H "role: teacher"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, student_id, roleStr)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	memberID, err := getInt32Id(headerVals[student_id])
+	if err != nil {
+		logAndSendError(w, err, "Invalid student id", http.StatusBadRequest)
+		return
+	}
+
+	newRole := headerVals[roleStr]
+	if newRole != teacher && newRole != student {
+		logAndSendError(w, errHeader, "Role must be student or teacher", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	if newRole == student {
+		if err = guardLastTeacher(ctx, qtx, classID, memberID); err != nil {
+			sendLastTeacherError(w, err)
+			return
+		}
+	}
+
+	rows, err := qtx.UpdateClassMemberRole(ctx, db.UpdateClassMemberRoleParams{
+		Role:    newRole,
+		ClassID: classID,
+		UserID:  memberID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to change role", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		logAndSendError(w, errors.New("not a member"), "User is not a member of this class", http.StatusNotFound)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode("Role updated"); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// a teacher makes a student of the class a teacher; they have to have joined it first.
+// Same as /role with role: teacher, which replaced it
+func (h *DBHandler) GrantClassTeacher(w http.ResponseWriter, r *http.Request) {
+	// curl -X PUT localhost:8000/api/classes/teacher -H "id: 1" -H "student_id: 5"
+
+	r.Header.Set(roleStr, teacher)
+	h.UpdateClassMemberRole(w, r)
+}
+
+// hands the class to another member: they become a teacher and the caller a student
+func (h *DBHandler) TransferClass(w http.ResponseWriter, r *http.Request) {
+	// curl -X PUT localhost:8000/api/classes/transfer -H "id: 1" -H "student_id: 5"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, student_id)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	memberID, err := getInt32Id(headerVals[student_id])
+	if err != nil {
+		logAndSendError(w, err, "Invalid student id", http.StatusBadRequest)
+		return
+	}
+
+	if memberID == userID {
+		logAndSendError(w, errors.New("transfer to self"), "Choose another member of the class", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	// promote first so the class is never without a teacher
+	rows, err := qtx.UpdateClassMemberRole(ctx, db.UpdateClassMemberRoleParams{
+		Role:    teacher,
+		ClassID: classID,
+		UserID:  memberID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to transfer class", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		logAndSendError(w, errors.New("not a member"), "User is not a member of this class", http.StatusNotFound)
+		return
+	}
+
+	_, err = qtx.UpdateClassMemberRole(ctx, db.UpdateClassMemberRoleParams{
+		Role:    student,
+		ClassID: classID,
+		UserID:  userID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to transfer class", http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode("Class transferred"); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// members can leave on their own and teachers can remove anyone, but never the last teacher
 func (h *DBHandler) LeaveClass(w http.ResponseWriter, r *http.Request) {
 	//curl -X DELETE localhost:8000/api/class_user/ -H "user_id: 1" -H "class_id: 1"
 
@@ -110,12 +308,32 @@ func (h *DBHandler) LeaveClass(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	err = query.LeaveClass(ctx, params)
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	if err = guardLastTeacher(ctx, qtx, classID, params.UserID); err != nil {
+		sendLastTeacherError(w, err)
+		return
+	}
+
+	err = qtx.LeaveClass(ctx, params)
 	if err != nil {
 		logAndSendError(w, err, "Error leaving class", http.StatusInternalServerError)
 		return
 	}
 
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
 	// no body is sent with a 204 response
 	w.WriteHeader(http.StatusNoContent)
 	w.Write([]byte{})
@@ -151,7 +369,7 @@ func (h *DBHandler) ListClassesOfAUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *DBHandler) ListMembersOfAClass(w http.ResponseWriter, r *http.Request) {
-	//curl -X GET localhost:8000/api/class_user/getmembers -H "class_id: 1"
+	//curl -X GET --cookie "cookie" localhost:8000/api/class_user/members -H "class_id: 1"
 
 	query, ctx, conn, err := getQueryConnAndContext(r, h)
 	if err != nil {
@@ -160,90 +378,144 @@ func (h *DBHandler) ListMembersOfAClass(w http.ResponseWriter, r *http.Request)
 	}
 	defer conn.Release()
 
-	headerVals, err := getHeaderVals(r, class_id)
-	if err != nil {
-		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	classID, err := getInt32Id(headerVals[class_id])
-	if err != nil {
-		logAndSendError(w, err, "Invalid class id", http.StatusBadRequest)
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || (role != teacher && role != student) {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	members, err := query.ListMembersOfAClass(ctx, classID)
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := query.ListMembersOfAClass(ctx, db.ListMembersOfAClassParams{
+		ClassID:   classID,
+		IsTeacher: role == teacher,
+		ViewerID:  userID,
+	})
 	if err != nil {
 		logAndSendError(w, err, "Error getting members", http.StatusInternalServerError)
 		return
 	}
 
+	members := make([]ClassMember, len(rows))
+	for i, row := range rows {
+		members[i] = ClassMember{
+			UserID:    row.UserID,
+			ClassID:   row.ClassID,
+			Role:      row.Role,
+			FirstName: row.FirstName,
+			LastName:  row.LastName,
+			Username:  row.Username,
+		}
+		if role != teacher && row.UserID != userID {
+			members[i].FirstName, members[i].LastName, members[i].Username, members[i].UserID = leaderboardName(
+				strictestVisibility(row.LeaderboardVisibility, row.ClassVisibility), classID, row.NicknameSeed,
+				row.FirstName, row.LastName, row.Username, row.UserID,
+			)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(members); err != nil {
 		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
 	}
 }
 
-// func (h *DBHandler) GetStudentsOfAClass(w http.ResponseWriter, r *http.Request) {
-// 	//curl -X GET localhost:8000/api/class_user/getstudents -H "class_id: 1"
-// 	query, ctx, conn, err := getQueryConnAndContext(r, h)
-// 	if err != nil {
-// 		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
-// 		return
-// 	}
-// 	defer conn.Release()
-
-// 	headerVals, err := getHeaderVals(r, "class_id")
-// 	if err != nil {
-// 		logAndSendError(w, err, "Header error", http.StatusBadRequest)
-// 		return
-// 	}
-
-// 	cid, err := getInt32Id(headerVals["class_id"])
-// 	if err != nil {
-// 		logAndSendError(w, err, "Invalid class id", http.StatusBadRequest)
-// 		return
-// 	}
-
-// 	students, err := query.GetStudentsOfAClass(ctx, cid)
-// 	if err != nil {
-// 		logAndSendError(w, err, "Error getting students", http.StatusInternalServerError)
-// 		return
-// 	}
-
-// 	if err := json.NewEncoder(w).Encode(students); err != nil {
-// 		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
-// 	}
-// }
-
-// func (h *DBHandler) GetTeacherOfAClass(w http.ResponseWriter, r *http.Request) {
-// 	//curl -X GET localhost:8000/api/class_user/getstudents -H "class_id: 1"
-// 	query, ctx, conn, err := getQueryConnAndContext(r, h)
-// 	if err != nil {
-// 		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
-// 		return
-// 	}
-// 	defer conn.Release()
-
-// 	headerVals, err := getHeaderVals(r, "class_id")
-// 	if err != nil {
-// 		logAndSendError(w, err, "Header error", http.StatusBadRequest)
-// 		return
-// 	}
-
-// 	cid, err := getInt32Id(headerVals["class_id"])
-// 	if err != nil {
-// 		logAndSendError(w, err, "Invalid class id", http.StatusBadRequest)
-// 		return
-// 	}
-
-// 	teacher, err := query.GetTeacherOfAClass(ctx, cid)
-// 	if err != nil {
-// 		logAndSendError(w, err, "Error getting teacher", http.StatusInternalServerError)
-// 		return
-// 	}
-
-// 	if err := json.NewEncoder(w).Encode(teacher); err != nil {
-// 		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
-// 	}
-// }
+func (h *DBHandler) ListStudentsOfAClass(w http.ResponseWriter, r *http.Request) {
+	//curl -X GET localhost:8000/api/classes/students -H "id: 1"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	students, err := query.ListStudentsOfAClass(ctx, classID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting students", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(students); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+func (h *DBHandler) ListTeachersOfAClass(w http.ResponseWriter, r *http.Request) {
+	//curl -X GET localhost:8000/api/classes/teachers -H "id: 1"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	teachers, err := query.ListTeachersOfAClass(ctx, classID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting teachers", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(teachers); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// guardLastTeacher fails with errLastTeacher when userID is the class's only teacher.
+// query must be bound to a tx, the teacher rows stay locked until it ends
+func guardLastTeacher(ctx context.Context, query *db.Queries, classID, userID int32) error {
+	teachers, err := query.LockTeachersOfAClass(ctx, classID)
+	if err != nil {
+		return err
+	}
+	if len(teachers) == 1 && teachers[0] == userID {
+		return errLastTeacher
+	}
+	return nil
+}
+
+func sendLastTeacherError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errLastTeacher) {
+		logAndSendError(w, err, "A class must keep at least one teacher", http.StatusConflict)
+		return
+	}
+	logAndSendError(w, err, "Error checking teachers", http.StatusInternalServerError)
+}
@@ -75,6 +75,7 @@ func (h *DBHandler) GetClassById(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// lifetime scores, with the same privacy rules as the windowed leaderboards
 func (h *DBHandler) GetClassLeaderboard(w http.ResponseWriter, r *http.Request) {
 	// curl http://localhost:8000/api/classes/leaderboard -H "id: 1"
 
@@ -85,18 +86,52 @@ func (h *DBHandler) GetClassLeaderboard(w http.ResponseWriter, r *http.Request)
 	}
 	defer conn.Release()
 
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || (role != teacher && role != student) {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	classID, ok := middleware.GetClassIDFromContext(ctx)
 	if !ok {
 		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	scores, err := query.GetClassLeaderboard(ctx, classID)
+	rows, err := query.GetClassLeaderboard(ctx, db.GetClassLeaderboardParams{
+		IsTeacher: role == teacher,
+		ViewerID:  userID,
+		ClassID:   classID,
+	})
 	if err != nil {
 		logAndSendError(w, err, "Error getting scores", http.StatusInternalServerError)
 		return
 	}
 
+	scores := make([]ClassLeaderboardEntry, len(rows))
+	for i, row := range rows {
+		scores[i] = ClassLeaderboardEntry{
+			UserID:     row.UserID,
+			FirstName:  row.FirstName,
+			LastName:   row.LastName,
+			Username:   row.Username,
+			ClassScore: row.ClassScore,
+		}
+		if role != teacher && row.UserID != userID {
+			scores[i].FirstName, scores[i].LastName, scores[i].Username, scores[i].UserID = leaderboardName(
+				strictestVisibility(row.LeaderboardVisibility, row.ClassVisibility), classID, row.NicknameSeed,
+				row.FirstName, row.LastName, row.Username, row.UserID,
+			)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err = json.NewEncoder(w).Encode(scores); err != nil {
 		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
@@ -154,6 +189,12 @@ func (h *DBHandler) CreateClass(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	_, err = regenerateJoinCode(ctx, tx, query, db.RegenerateJoinCodeParams{ClassID: class.ID})
+	if err != nil {
+		logAndSendError(w, err, "Failed to create join code", http.StatusInternalServerError)
+		return
+	}
+
 	err = tx.Commit(ctx)
 	if err != nil {
 		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
@@ -225,6 +266,62 @@ func (h *DBHandler) UpdateClass(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *DBHandler) UpdateClassMasteryRule(w http.ResponseWriter, r *http.Request) {
+	// curl -X PUT http://localhost:8000/api/classes/mastery -H "id: 1" -H "mastery_streak: 4" -H "mastery_interval_days: 30"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "context error", http.StatusInternalServerError)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, mastery_streak, mastery_interval)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	streak, err := getMasteryThreshold(headerVals[mastery_streak])
+	if err != nil {
+		logAndSendError(w, err, "Invalid mastery streak", http.StatusBadRequest)
+		return
+	}
+
+	interval, err := getMasteryThreshold(headerVals[mastery_interval])
+	if err != nil {
+		logAndSendError(w, err, "Invalid mastery interval", http.StatusBadRequest)
+		return
+	}
+
+	// applies to each student from their next review of a card in the class
+	err = query.UpdateClassMasteryRule(ctx, db.UpdateClassMasteryRuleParams{
+		MasteryStreak:       streak,
+		MasteryIntervalDays: interval,
+		ID:                  classID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to update mastery rule", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode("Mastery rule updated"); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
 func (h *DBHandler) DeleteClass(w http.ResponseWriter, r *http.Request) {
 	// curl -X DELETE http://localhost:8000/api/classes/ -H "id: 2"
 
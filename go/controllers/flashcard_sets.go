@@ -1,15 +1,34 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
 	"path"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
 	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// Sets are private to their members, class-only (the default: members of classes the set has
+// been added to can read it too), unlisted (also anyone with the link token) or public.
+const (
+	setPrivate  string = "private"
+	setClass    string = "class"
+	setUnlisted string = "unlisted"
+	setPublic   string = "public"
+)
+
+var setVisibilityLevels = []string{setPrivate, setClass, setUnlisted, setPublic}
+
+// public sets and the ones the user can read through membership or a class
 func (h *DBHandler) ListFlashcardSets(w http.ResponseWriter, r *http.Request) {
 	// curl http://localhost:8000/api/flashcards/sets/list | jq
 
@@ -20,7 +39,14 @@ func (h *DBHandler) ListFlashcardSets(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Release()
 
-	flashcard_sets, err := query.ListFlashcardSets(ctx)
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	flashcard_sets, err := query.ListFlashcardSets(ctx, userID)
 	if err != nil {
 		logAndSendError(w, err, "Error getting flashcard sets from DB", http.StatusInternalServerError)
 		return
@@ -32,8 +58,9 @@ func (h *DBHandler) ListFlashcardSets(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// unlisted sets are opened with the link token in the token header
 func (h *DBHandler) GetFlashcardSetById(w http.ResponseWriter, r *http.Request) {
-	// curl http://localhost:8000/api/flashcards/sets/ -H "id: 1"
+	// curl http://localhost:8000/api/flashcards/sets/ -H "id: 1" -H "token: share-token"
 
 	query, ctx, conn, err := getQueryConnAndContext(r, h)
 	if err != nil {
@@ -54,6 +81,22 @@ func (h *DBHandler) GetFlashcardSetById(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	readable, err := canReadSet(ctx, query, r, setID, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to get flashcard set", http.StatusInternalServerError)
+		return
+	} else if !readable {
+		logAndSendError(w, errSetNotFound, "Flashcard set not found", http.StatusNotFound)
+		return
+	}
+
 	flashcard_set, err := query.GetFlashcardSetById(ctx, setID)
 	if err != nil {
 		logAndSendError(w, err, "Failed to get flashcard set", http.StatusInternalServerError)
@@ -67,6 +110,10 @@ func (h *DBHandler) GetFlashcardSetById(w http.ResponseWriter, r *http.Request)
 		CreatedAt:      flashcard_set.CreatedAt.Time.Format(time.DateTime),
 		UpdatedAt:      flashcard_set.UpdatedAt.Time.Format(time.DateTime),
 		Role:           role,
+		Visibility:     flashcard_set.Visibility,
+	}
+	if role == owner {
+		response.ShareToken = flashcard_set.ShareToken.String
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -106,6 +153,12 @@ func (h *DBHandler) CreateFlashcardSet(w http.ResponseWriter, r *http.Request) {
 
 	qtx := query.WithTx(tx)
 
+	err = qtx.SetRevisionAuthor(ctx, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to set revision author", http.StatusInternalServerError)
+		return
+	}
+
 	flashcard_set, err := qtx.CreateFlashcardSet(ctx, db.CreateFlashcardSetParams{
 		SetName:        headerVals[set_name],
 		SetDescription: headerVals[set_description],
@@ -148,6 +201,12 @@ func (h *DBHandler) UpdateFlashcardSet(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Release()
 
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != owner {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	route := path.Base(r.URL.Path)
 
 	headerVals, err := getHeaderVals(r, route)
@@ -162,17 +221,39 @@ func (h *DBHandler) UpdateFlashcardSet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	val := headerVals[route]
 
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	err = qtx.SetRevisionAuthor(ctx, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to set revision author", http.StatusInternalServerError)
+		return
+	}
+
 	var res string
 	switch route {
 	case set_name:
-		res, err = query.UpdateFlashcardSetName(ctx, db.UpdateFlashcardSetNameParams{
+		res, err = qtx.UpdateFlashcardSetName(ctx, db.UpdateFlashcardSetNameParams{
 			SetName: val,
 			ID:      setID,
 		})
 	case set_description:
-		res, err = query.UpdateFlashcardSetDescription(ctx, db.UpdateFlashcardSetDescriptionParams{
+		res, err = qtx.UpdateFlashcardSetDescription(ctx, db.UpdateFlashcardSetDescriptionParams{
 			SetDescription: val,
 			ID:             setID,
 		})
@@ -185,11 +266,182 @@ func (h *DBHandler) UpdateFlashcardSet(w http.ResponseWriter, r *http.Request) {
 		logAndSendError(w, err, "Failed to update flashcard set", http.StatusInternalServerError)
 		return
 	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
 	if err := json.NewEncoder(w).Encode(res); err != nil {
 		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
 	}
 }
 
+func (h *DBHandler) UpdateFlashcardSetMasteryRule(w http.ResponseWriter, r *http.Request) {
+	// curl -X PUT localhost:8000/api/flashcards/sets/mastery -H "id: 1" -H "mastery_streak: 5" -H "mastery_interval_days: inherit"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != owner {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	setID, ok := middleware.GetSetIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, mastery_streak, mastery_interval)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	streak, err := getMasteryThreshold(headerVals[mastery_streak])
+	if err != nil {
+		logAndSendError(w, err, "Invalid mastery streak", http.StatusBadRequest)
+		return
+	}
+
+	interval, err := getMasteryThreshold(headerVals[mastery_interval])
+	if err != nil {
+		logAndSendError(w, err, "Invalid mastery interval", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	err = qtx.SetRevisionAuthor(ctx, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to set revision author", http.StatusInternalServerError)
+		return
+	}
+
+	err = qtx.UpdateFlashcardSetMasteryRule(ctx, db.UpdateFlashcardSetMasteryRuleParams{
+		MasteryStreak:       streak,
+		MasteryIntervalDays: interval,
+		ID:                  setID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to update mastery rule", http.StatusInternalServerError)
+		return
+	}
+
+	// a fully specified override is the same for every student, anything inherited
+	// depends on their classes and is settled on their next review
+	if streak.Valid && interval.Valid {
+		err = qtx.RecomputeMasteryInASet(ctx, db.RecomputeMasteryInASetParams{
+			Streak:       streak.Int32,
+			IntervalDays: interval.Int32,
+			SetID:        setID,
+		})
+		if err != nil {
+			logAndSendError(w, err, "Failed to recompute mastery", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode("Mastery rule updated"); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// making a set unlisted returns the token for its link. Changing away from unlisted throws the
+// token out, so old links stop working
+func (h *DBHandler) UpdateFlashcardSetVisibility(w http.ResponseWriter, r *http.Request) {
+	// curl -X PUT localhost:8000/api/flashcards/sets/visibility -H "id: 1" -H "visibility: unlisted"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != owner {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	setID, ok := middleware.GetSetIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, visibility)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	level := headerVals[visibility]
+	if !slices.Contains(setVisibilityLevels, level) {
+		err = fmt.Errorf("visibility must be one of %s", strings.Join(setVisibilityLevels, ", "))
+		logAndSendError(w, err, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// only used if the set wasn't already unlisted
+	var shareToken pgtype.Text
+	if level == setUnlisted {
+		newToken, err := generateUniqueToken()
+		if err != nil {
+			logAndSendError(w, err, "Failed to generate link", http.StatusInternalServerError)
+			return
+		}
+		shareToken = pgtype.Text{String: newToken, Valid: true}
+	}
+
+	res, err := query.UpdateFlashcardSetVisibility(ctx, db.UpdateFlashcardSetVisibilityParams{
+		Visibility: level,
+		ShareToken: shareToken,
+		ID:         setID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to update visibility", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(SetVisibilityResponse{
+		Visibility: res.Visibility,
+		ShareToken: res.ShareToken.String,
+	}); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
 func (h *DBHandler) DeleteFlashcardSet(w http.ResponseWriter, r *http.Request) {
 	// curl -X DELETE http://localhost:8000/api/flashcards/sets -H "id: 1"
 
@@ -200,6 +452,12 @@ func (h *DBHandler) DeleteFlashcardSet(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Release()
 
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != owner {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	setID, ok := middleware.GetSetIDFromContext(ctx)
 	if !ok {
 		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
@@ -216,3 +474,95 @@ func (h *DBHandler) DeleteFlashcardSet(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 	w.Write([]byte{})
 }
+
+func (h *DBHandler) UpdateFlashcardSetTypoTolerance(w http.ResponseWriter, r *http.Request) {
+	// curl -X PUT localhost:8000/api/flashcards/sets/typo_tolerance -H "id: 1" -H "typo_tolerance: 2"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != owner {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	setID, ok := middleware.GetSetIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, typo_tolerance)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	// 0 means typed answers must match exactly (ignoring case, accents and punctuation)
+	tolerance, err := strconv.Atoi(headerVals[typo_tolerance])
+	if err != nil || tolerance < 0 || tolerance > math.MaxInt32 {
+		logAndSendError(w, errHeader, "Invalid typo tolerance", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	err = qtx.SetRevisionAuthor(ctx, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to set revision author", http.StatusInternalServerError)
+		return
+	}
+
+	err = qtx.UpdateFlashcardSetTypoTolerance(ctx, db.UpdateFlashcardSetTypoToleranceParams{
+		TypoTolerance: int32(tolerance),
+		ID:            setID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to update typo tolerance", http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode("Typo tolerance updated"); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// canReadSet checks the set's visibility for the user. The token header carries the link token
+// of an unlisted set and is ignored otherwise
+func canReadSet(ctx context.Context, query *db.Queries, r *http.Request, setID, userID int32) (bool, error) {
+	shareToken := ""
+	if vals, err := getHeaderVals(r, token); err == nil {
+		shareToken = vals[token]
+	}
+
+	return query.CanReadSet(ctx, db.CanReadSetParams{
+		SetID:      setID,
+		ShareToken: shareToken,
+		UserID:     userID,
+	})
+}
@@ -2,8 +2,11 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"path"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
 	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
@@ -31,12 +34,33 @@ func (h *DBHandler) GetFlashcardById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	flashcard, err := query.GetFlashcardById(ctx, id)
 	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			logAndSendError(w, err, "Flashcard not found", http.StatusNotFound)
+			return
+		}
 		logAndSendError(w, err, "Failed to get flashcard", http.StatusInternalServerError)
 		return
 	}
 
+	// a card the user can't read looks the same as one that doesn't exist
+	readable, err := canReadSet(ctx, query, r, flashcard.SetID, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to get flashcard", http.StatusInternalServerError)
+		return
+	} else if !readable {
+		logAndSendError(w, errSetNotFound, "Flashcard not found", http.StatusNotFound)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(flashcard); err != nil {
 		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
@@ -44,7 +68,7 @@ func (h *DBHandler) GetFlashcardById(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *DBHandler) ListFlashcardsOfASet(w http.ResponseWriter, r *http.Request) {
-	// curl http://localhost:8000/api/flashcards/list -H "set_id:1"
+	// curl http://localhost:8000/api/flashcards/list -H "set_id:1" -H "token: share-token"
 
 	query, ctx, conn, err := getQueryConnAndContext(r, h)
 	if err != nil {
@@ -65,6 +89,22 @@ func (h *DBHandler) ListFlashcardsOfASet(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	readable, err := canReadSet(ctx, query, r, set_id, userID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting flashcards from DB", http.StatusInternalServerError)
+		return
+	} else if !readable {
+		logAndSendError(w, errSetNotFound, "Flashcard set not found", http.StatusNotFound)
+		return
+	}
+
 	flashcards, err := query.ListFlashcardsOfASet(ctx, set_id)
 	if err != nil {
 		logAndSendError(w, err, "Error getting flashcards from DB", http.StatusInternalServerError)
@@ -93,6 +133,11 @@ func (h *DBHandler) CreateFlashcard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if utf8.RuneCountInString(headerVals[front]) > maxCardSide || utf8.RuneCountInString(headerVals[back]) > maxCardSide {
+		logAndSendError(w, errHeader, fmt.Sprintf("Terms and definitions are limited to %d characters", maxCardSide), http.StatusBadRequest)
+		return
+	}
+
 	setID, ok := middleware.GetSetIDFromContext(ctx)
 	if !ok {
 		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
@@ -105,7 +150,29 @@ func (h *DBHandler) CreateFlashcard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	flashcard, err := query.CreateFlashcard(ctx, db.CreateFlashcardParams{
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	err = qtx.SetRevisionAuthor(ctx, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to set revision author", http.StatusInternalServerError)
+		return
+	}
+
+	flashcard, err := qtx.CreateFlashcard(ctx, db.CreateFlashcardParams{
 		Front: headerVals[front],
 		Back:  headerVals[back],
 		SetID: setID,
@@ -115,6 +182,12 @@ func (h *DBHandler) CreateFlashcard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(flashcard); err != nil {
@@ -132,33 +205,66 @@ func (h *DBHandler) UpdateFlashcard(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Release()
 
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != owner {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	route := path.Base(r.URL.Path)
 
-	headerVals, err := getHeaderVals(r, route)
+	headerVals, err := getHeaderVals(r, id, route)
 	if err != nil {
 		logAndSendError(w, err, "Header error", http.StatusBadRequest)
 		return
 	}
 
-	setID, ok := middleware.GetSetIDFromContext(ctx)
+	// the mw already checked the card exists and resolved its set
+	cardID, err := getInt32Id(headerVals[id])
+	if err != nil {
+		logAndSendError(w, err, "Invalid id", http.StatusBadRequest)
+		return
+	}
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
 	if !ok {
 		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	val := headerVals[route]
+	if utf8.RuneCountInString(val) > maxCardSide {
+		logAndSendError(w, errHeader, fmt.Sprintf("Terms and definitions are limited to %d characters", maxCardSide), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	err = qtx.SetRevisionAuthor(ctx, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to set revision author", http.StatusInternalServerError)
+		return
+	}
 
 	var res string
 	switch route {
 	case front:
-		res, err = query.UpdateFlashcardFront(ctx, db.UpdateFlashcardFrontParams{
+		res, err = qtx.UpdateFlashcardFront(ctx, db.UpdateFlashcardFrontParams{
 			Front: val,
-			ID:    setID,
+			ID:    cardID,
 		})
 	case back:
-		res, err = query.UpdateFlashcardBack(ctx, db.UpdateFlashcardBackParams{
+		res, err = qtx.UpdateFlashcardBack(ctx, db.UpdateFlashcardBackParams{
 			Back: val,
-			ID:   setID,
+			ID:   cardID,
 		})
 	default:
 		logAndSendError(w, errHeader, "Improper header", http.StatusBadRequest)
@@ -169,6 +275,13 @@ func (h *DBHandler) UpdateFlashcard(w http.ResponseWriter, r *http.Request) {
 		logAndSendError(w, err, "Failed to update flashcard", http.StatusInternalServerError)
 		return
 	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
 	if err := json.NewEncoder(w).Encode(res); err != nil {
 		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
 	}
@@ -184,18 +297,58 @@ func (h *DBHandler) DeleteFlashcard(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Release()
 
-	setID, ok := middleware.GetSetIDFromContext(ctx)
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != owner {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, id)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	cardID, err := getInt32Id(headerVals[id])
+	if err != nil {
+		logAndSendError(w, err, "Invalid id", http.StatusBadRequest)
+		return
+	}
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
 	if !ok {
 		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	err = query.DeleteFlashcard(ctx, setID)
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	err = qtx.SetRevisionAuthor(ctx, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to set revision author", http.StatusInternalServerError)
+		return
+	}
+
+	err = qtx.DeleteFlashcard(ctx, cardID)
 	if err != nil {
 		logAndSendError(w, err, "Failed to delete flashcard", http.StatusInternalServerError)
 		return
 	}
 
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
 	// no body is sent with a 204 response
 	w.WriteHeader(http.StatusNoContent)
 	w.Write([]byte{})
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// copies a set the caller can read, cards and settings, into a new set they own. The copy
+// starts class-only whatever the original's visibility, and remembers where it came from
+// so it can be compared with the original later
+func (h *DBHandler) ForkFlashcardSet(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/flashcards/sets/fork -H "set_id: 1" -H "set_name: My Knights"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	upstreamID, ok := middleware.GetSetIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	readable, err := canReadSet(ctx, query, r, upstreamID, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to get flashcard set", http.StatusInternalServerError)
+		return
+	} else if !readable {
+		logAndSendError(w, errSetNotFound, "Flashcard set not found", http.StatusNotFound)
+		return
+	}
+
+	var name pgtype.Text
+	if vals, err := getHeaderVals(r, set_name); err == nil {
+		name = pgtype.Text{String: vals[set_name], Valid: true}
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	err = qtx.SetRevisionAuthor(ctx, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to set revision author", http.StatusInternalServerError)
+		return
+	}
+
+	fork, err := qtx.ForkFlashcardSet(ctx, db.ForkFlashcardSetParams{
+		SetName:       name,
+		UpstreamSetID: upstreamID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to fork flashcard set", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = qtx.ForkFlashcards(ctx, db.ForkFlashcardsParams{
+		ForkSetID:     fork.ID,
+		UpstreamSetID: upstreamID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to copy flashcards", http.StatusInternalServerError)
+		return
+	}
+
+	err = qtx.JoinSet(ctx, db.JoinSetParams{
+		UserID: userID,
+		SetID:  fork.ID,
+		Role:   owner,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error adding set", http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(fork); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// what changed in the original since the fork: cards it has that the fork doesn't, cards
+// edited there since the fork and cards it no longer has. The original has to still be
+// readable by the caller; an unlisted one needs its link token in the token header
+func (h *DBHandler) DiffForkWithUpstream(w http.ResponseWriter, r *http.Request) {
+	// curl localhost:8000/api/flashcards/sets/fork/diff -H "id: 2"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || (role != owner && role != user) {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	setID, ok := middleware.GetSetIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	fork, err := query.GetFlashcardSetById(ctx, setID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to get flashcard set", http.StatusInternalServerError)
+		return
+	}
+
+	if !fork.ForkedFrom.Valid {
+		logAndSendError(w, errors.New("not a fork"), "This set isn't a fork, or the set it was forked from was deleted", http.StatusNotFound)
+		return
+	}
+
+	readable, err := canReadSet(ctx, query, r, fork.ForkedFrom.Int32, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to get flashcard set", http.StatusInternalServerError)
+		return
+	} else if !readable {
+		logAndSendError(w, errSetNotFound, "The set this was forked from is no longer visible", http.StatusNotFound)
+		return
+	}
+
+	changes, err := query.DiffForkWithUpstream(ctx, db.DiffForkWithUpstreamParams{
+		SetID:         setID,
+		UpstreamSetID: fork.ForkedFrom.Int32,
+		ForkedAt:      fork.ForkedAt,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error comparing with the original set", http.StatusInternalServerError)
+		return
+	}
+
+	res := ForkDiffResponse{
+		UpstreamSetID: fork.ForkedFrom.Int32,
+		ForkedAt:      fork.ForkedAt.Time,
+		New:           []ForkDiffCard{},
+		Changed:       []ForkDiffCard{},
+		Removed:       []ForkDiffCard{},
+	}
+	for _, change := range changes {
+		card := ForkDiffCard{
+			CardID:         change.CardID,
+			Front:          change.Front,
+			Back:           change.Back,
+			UpstreamCardID: change.UpstreamCardID,
+			UpstreamFront:  change.UpstreamFront,
+			UpstreamBack:   change.UpstreamBack,
+		}
+		switch change.Change {
+		case "new":
+			res.New = append(res.New, card)
+		case "changed":
+			res.Changed = append(res.Changed, card)
+		case "removed":
+			res.Removed = append(res.Removed, card)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
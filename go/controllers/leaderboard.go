@@ -0,0 +1,416 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Windowed leaderboards score correct answers from review_log inside the window instead of
+// the lifetime set_score, so late joiners can still top the week. Weeks start on Monday.
+//
+// What other members see of someone is the stricter of their own visibility and the class's:
+// full name, username only, a nickname, or nothing at all. Teachers always see real names,
+// and everyone always sees themselves.
+
+const (
+	week   string = "week"
+	month  string = "month"
+	custom string = "custom"
+
+	visibilityFull     string = "full"
+	visibilityUsername string = "username"
+	visibilityNickname string = "nickname"
+	visibilityHidden   string = "hidden"
+)
+
+// from most to least revealing
+var visibilityLevels = []string{visibilityFull, visibilityUsername, visibilityNickname, visibilityHidden}
+
+var (
+	nicknameAdjectives = []string{
+		"Bold", "Brave", "Clever", "Dusty", "Gritty", "Lonesome", "Lucky", "Quick",
+		"Quiet", "Rowdy", "Rusty", "Sly", "Steady", "Sunny", "Swift", "Wild",
+	}
+	nicknameAnimals = []string{
+		"Armadillo", "Bison", "Bronco", "Burro", "Coyote", "Fox", "Hawk", "Jackrabbit",
+		"Lizard", "Longhorn", "Mustang", "Owl", "Prairie Dog", "Rattler", "Roadrunner", "Tortoise",
+	}
+)
+
+func (h *DBHandler) ListClassLeaderboard(w http.ResponseWriter, r *http.Request) {
+	// curl http://localhost:8000/api/classes/leaderboard/window -H "id: 1" -H "window: custom" -H "from: 2025-04-01T00:00:00-05:00" -H "to: 2025-05-01T00:00:00-05:00" -H "page: 2"
+
+	start, end, err := getLeaderboardWindow(r, time.Now())
+	if err != nil {
+		logAndSendError(w, err, "Invalid window", http.StatusBadRequest)
+		return
+	}
+
+	page, pageSize, err := getPage(r)
+	if err != nil {
+		logAndSendError(w, err, "Invalid page", http.StatusBadRequest)
+		return
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || (role != teacher && role != student) {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// one extra row tells whether there's another page
+	rows, err := query.ListClassLeaderboard(ctx, db.ListClassLeaderboardParams{
+		WindowStart: pgtype.Timestamptz{Time: start, Valid: true},
+		WindowEnd:   pgtype.Timestamptz{Time: end, Valid: true},
+		IsTeacher:   role == teacher,
+		ViewerID:    userID,
+		ClassID:     classID,
+		PageOffset:  int32((page - 1) * pageSize),
+		PageSize:    int32(pageSize + 1),
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error getting scores", http.StatusInternalServerError)
+		return
+	}
+
+	res := newLeaderboardResponse(start, end, page, pageSize, len(rows))
+	for i, row := range rows[:len(res.Entries)] {
+		res.Entries[i] = LeaderboardEntry{
+			Rank:      (page-1)*pageSize + i + 1,
+			UserID:    row.UserID,
+			FirstName: row.FirstName,
+			LastName:  row.LastName,
+			Username:  row.Username,
+			Score:     row.Score,
+			Reviews:   row.Reviews,
+		}
+		if role != teacher && row.UserID != userID {
+			res.Entries[i].hideName(strictestVisibility(row.LeaderboardVisibility, row.ClassVisibility), classID, row.NicknameSeed)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// only users who opted in are listed, each at their own visibility
+func (h *DBHandler) ListGlobalLeaderboard(w http.ResponseWriter, r *http.Request) {
+	// curl http://localhost:8000/api/leaderboard/ -H "window: month" -H "page_size: 50"
+
+	start, end, err := getLeaderboardWindow(r, time.Now())
+	if err != nil {
+		logAndSendError(w, err, "Invalid window", http.StatusBadRequest)
+		return
+	}
+
+	page, pageSize, err := getPage(r)
+	if err != nil {
+		logAndSendError(w, err, "Invalid page", http.StatusBadRequest)
+		return
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := query.ListGlobalLeaderboard(ctx, db.ListGlobalLeaderboardParams{
+		WindowStart: pgtype.Timestamptz{Time: start, Valid: true},
+		WindowEnd:   pgtype.Timestamptz{Time: end, Valid: true},
+		PageOffset:  int32((page - 1) * pageSize),
+		PageSize:    int32(pageSize + 1),
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error getting scores", http.StatusInternalServerError)
+		return
+	}
+
+	res := newLeaderboardResponse(start, end, page, pageSize, len(rows))
+	for i, row := range rows[:len(res.Entries)] {
+		res.Entries[i] = LeaderboardEntry{
+			Rank:      (page-1)*pageSize + i + 1,
+			UserID:    row.UserID,
+			FirstName: row.FirstName,
+			LastName:  row.LastName,
+			Username:  row.Username,
+			Score:     row.Score,
+			Reviews:   row.Reviews,
+		}
+		if row.UserID != userID {
+			res.Entries[i].hideName(row.LeaderboardVisibility, 0, row.NicknameSeed)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+func (h *DBHandler) UpdateLeaderboardOptIn(w http.ResponseWriter, r *http.Request) {
+	// curl -X PUT http://localhost:8000/api/users/leaderboard_opt_in -H "opt_in: true"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, opt_in)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	optIn, err := strconv.ParseBool(headerVals[opt_in])
+	if err != nil {
+		logAndSendError(w, err, "Invalid opt_in", http.StatusBadRequest)
+		return
+	}
+
+	err = query.UpdateLeaderboardOptIn(ctx, db.UpdateLeaderboardOptInParams{
+		LeaderboardOptIn: optIn,
+		ID:               userID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to update leaderboard opt in", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(optIn); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+func (h *DBHandler) UpdateLeaderboardVisibility(w http.ResponseWriter, r *http.Request) {
+	// curl -X PUT http://localhost:8000/api/users/leaderboard_visibility -H "visibility: nickname"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	level, err := getVisibility(r)
+	if err != nil {
+		logAndSendError(w, err, "Invalid visibility", http.StatusBadRequest)
+		return
+	}
+
+	err = query.UpdateLeaderboardVisibility(ctx, db.UpdateLeaderboardVisibilityParams{
+		LeaderboardVisibility: level,
+		ID:                    userID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to update leaderboard visibility", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(level); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// the class setting is a floor: students can be more private than it, never less
+func (h *DBHandler) UpdateClassLeaderboardVisibility(w http.ResponseWriter, r *http.Request) {
+	// curl -X PUT http://localhost:8000/api/classes/leaderboard_visibility -H "id: 1" -H "visibility: username"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	level, err := getVisibility(r)
+	if err != nil {
+		logAndSendError(w, err, "Invalid visibility", http.StatusBadRequest)
+		return
+	}
+
+	err = query.UpdateClassLeaderboardVisibility(ctx, db.UpdateClassLeaderboardVisibilityParams{
+		LeaderboardVisibility: level,
+		ID:                    classID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to update leaderboard visibility", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(level); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// hideName reduces an entry to what others may see at visibility. Hidden users never
+// make it out of the queries, so they aren't handled here
+func (e *LeaderboardEntry) hideName(visibility string, scope, seed int32) {
+	e.FirstName, e.LastName, e.Username, e.UserID = leaderboardName(visibility, scope, seed, e.FirstName, e.LastName, e.Username, e.UserID)
+}
+
+// leaderboardName returns the names and id others may see of a user at visibility. A
+// nickname replaces the username and the id, which would give the user away. Nicknames come
+// from the user's secret seed and the scope (the class id, 0 for global), so they stay the
+// same between visits but can't be matched up across classes
+func leaderboardName(visibility string, scope, seed int32, first, last, username string, userID int32) (string, string, string, int32) {
+	switch visibility {
+	case visibilityFull:
+		return first, last, username, userID
+	case visibilityUsername:
+		return "", "", username, userID
+	}
+
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d:%d", seed, scope)
+	n := h.Sum32()
+
+	adjective := nicknameAdjectives[n%uint32(len(nicknameAdjectives))]
+	n /= uint32(len(nicknameAdjectives))
+	animal := nicknameAnimals[n%uint32(len(nicknameAnimals))]
+	n /= uint32(len(nicknameAnimals))
+
+	return "", "", fmt.Sprintf("%s %s %d", adjective, animal, n%90+10), 0
+}
+
+// strictestVisibility picks the least revealing of levels
+func strictestVisibility(levels ...string) string {
+	strictest := 0
+	for _, level := range levels {
+		strictest = max(strictest, slices.Index(visibilityLevels, level))
+	}
+	return visibilityLevels[strictest]
+}
+
+func getVisibility(r *http.Request) (string, error) {
+	headerVals, err := getHeaderVals(r, visibility)
+	if err != nil {
+		return "", err
+	}
+
+	if !slices.Contains(visibilityLevels, headerVals[visibility]) {
+		return "", fmt.Errorf("visibility must be one of %s", strings.Join(visibilityLevels, ", "))
+	}
+	return headerVals[visibility], nil
+}
+
+// newLeaderboardResponse sizes the entries for a page fetched with one extra row
+func newLeaderboardResponse(start, end time.Time, page, pageSize, fetched int) LeaderboardResponse {
+	n := min(fetched, pageSize)
+	return LeaderboardResponse{
+		WindowStart: start,
+		WindowEnd:   end,
+		Page:        page,
+		PageSize:    pageSize,
+		HasMore:     fetched > pageSize,
+		Entries:     make([]LeaderboardEntry, n),
+	}
+}
+
+// getLeaderboardWindow returns the [start, end) of the window header: the current week
+// (the default), the current month, or a custom range from the from/to headers
+func getLeaderboardWindow(r *http.Request, now time.Time) (start, end time.Time, err error) {
+	period := week
+	if vals, err := getHeaderVals(r, window); err == nil {
+		period = vals[window]
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch period {
+	case week:
+		start = today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7), nil
+	case month:
+		start = today.AddDate(0, 0, 1-today.Day())
+		return start, start.AddDate(0, 1, 0), nil
+	case custom:
+		vals, err := getHeaderVals(r, fromStr, toStr)
+		if err != nil {
+			return start, end, err
+		}
+		if start, err = time.Parse(time.RFC3339, vals[fromStr]); err != nil {
+			return start, end, err
+		}
+		if end, err = time.Parse(time.RFC3339, vals[toStr]); err != nil {
+			return start, end, err
+		}
+		if !end.After(start) {
+			return start, end, errors.New("to must be after from")
+		}
+		return start, end, nil
+	}
+	return start, end, fmt.Errorf("window must be %s, %s or %s", week, month, custom)
+}
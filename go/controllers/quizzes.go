@@ -0,0 +1,361 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/quiz"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/scheduler"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const maxQuizQuestions int = 100
+
+func (h *DBHandler) CreateQuiz(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/quizzes/ -H "set_id: 1" -H "count: 10"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, set_id, count)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	setID, err := getInt32Id(headerVals[set_id])
+	if err != nil {
+		logAndSendError(w, err, "Invalid set id", http.StatusBadRequest)
+		return
+	}
+
+	n, err := strconv.Atoi(headerVals[count])
+	if err != nil || n < 1 || n > maxQuizQuestions {
+		logAndSendError(w, errHeader, fmt.Sprintf("Count must be between 1 and %d", maxQuizQuestions), http.StatusBadRequest)
+		return
+	}
+
+	readable, err := canReadSet(ctx, query, r, setID, userID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting flashcards from DB", http.StatusInternalServerError)
+		return
+	} else if !readable {
+		logAndSendError(w, errSetNotFound, "Flashcard set not found", http.StatusNotFound)
+		return
+	}
+
+	flashcards, err := query.ListFlashcardsOfASet(ctx, setID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting flashcards from DB", http.StatusInternalServerError)
+		return
+	}
+
+	cards := make([]quiz.Card, len(flashcards))
+	for i, fc := range flashcards {
+		cards[i] = quiz.Card{ID: fc.ID, Front: fc.Front, Back: fc.Back}
+	}
+
+	// the seed is kept with the quiz but never leaves the server, with it the answers can be worked out
+	quizSeed := rand.Int64()
+	questions, err := quiz.Generate(cards, n, quizSeed)
+	if err != nil {
+		logAndSendError(w, err, "Not enough flashcards for a quiz", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	created, err := qtx.CreateQuiz(ctx, db.CreateQuizParams{
+		UserID: userID,
+		SetID:  setID,
+		Seed:   quizSeed,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error creating quiz", http.StatusInternalServerError)
+		return
+	}
+
+	res := QuizResponse{
+		ID:        created.ID,
+		SetID:     created.SetID,
+		Questions: make([]QuizQuestion, len(questions)),
+	}
+
+	for i, q := range questions {
+		err = qtx.AddQuizQuestion(ctx, db.AddQuizQuestionParams{
+			QuizID:      created.ID,
+			Position:    int32(i),
+			CardID:      q.CardID,
+			Prompt:      q.Prompt,
+			Choices:     q.Choices,
+			AnswerIndex: q.Answer,
+		})
+		if err != nil {
+			logAndSendError(w, err, "Error creating quiz", http.StatusInternalServerError)
+			return
+		}
+
+		res.Questions[i] = QuizQuestion{
+			Position: int32(i),
+			CardID:   q.CardID,
+			Prompt:   q.Prompt,
+			Choices:  q.Choices,
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// answers are only shown for questions that have been answered
+func (h *DBHandler) GetQuiz(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/quizzes/ -H "id: 1"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, id)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	quizID, err := getInt32Id(headerVals[id])
+	if err != nil {
+		logAndSendError(w, err, "Invalid quiz id", http.StatusBadRequest)
+		return
+	}
+
+	found, err := query.GetQuiz(ctx, db.GetQuizParams{
+		ID:     quizID,
+		UserID: userID,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			logAndSendError(w, err, "Quiz not found", http.StatusNotFound)
+			return
+		}
+		logAndSendError(w, err, "Error getting quiz", http.StatusInternalServerError)
+		return
+	}
+
+	questions, err := query.ListQuizQuestions(ctx, quizID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting quiz questions", http.StatusInternalServerError)
+		return
+	}
+
+	res := QuizResponse{
+		ID:        found.ID,
+		SetID:     found.SetID,
+		Questions: make([]QuizQuestion, len(questions)),
+	}
+	for i, q := range questions {
+		res.Questions[i] = QuizQuestion{
+			Position: q.Position,
+			CardID:   q.CardID,
+			Prompt:   q.Prompt,
+			Choices:  q.Choices,
+		}
+		if q.ChosenIndex.Valid {
+			res.Questions[i].Chosen = &q.ChosenIndex.Int32
+			res.Questions[i].Answer = &q.AnswerIndex
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// each question can be answered once; the grade goes through the same path as a
+// study review, so quizzes count towards card_history, the schedule and review_log
+func (h *DBHandler) AnswerQuiz(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/quizzes/answer -d '{"quiz_id": 1, "answers": [{"position": 0, "choice": 2, "response_ms": 3100}]}'
+
+	var req QuizAnswerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logAndSendError(w, err, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Answers) == 0 || len(req.Answers) > maxQuizQuestions {
+		logAndSendError(w, errors.New("answer count"), fmt.Sprintf("Send between 1 and %d answers", maxQuizQuestions), http.StatusBadRequest)
+		return
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	_, err = query.GetQuiz(ctx, db.GetQuizParams{
+		ID:     req.QuizID,
+		UserID: userID,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			logAndSendError(w, err, "Quiz not found", http.StatusNotFound)
+			return
+		}
+		logAndSendError(w, err, "Error getting quiz", http.StatusInternalServerError)
+		return
+	}
+
+	questions, err := query.ListQuizQuestions(ctx, req.QuizID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting quiz questions", http.StatusInternalServerError)
+		return
+	}
+
+	// a set with few distinct backs gives questions with fewer than quiz.MaxChoices choices
+	choiceCounts := make(map[int32]int, len(questions))
+	for _, q := range questions {
+		choiceCounts[q.Position] = len(q.Choices)
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	results := make([]QuizAnswerResult, len(req.Answers))
+	for i, answer := range req.Answers {
+		results[i] = QuizAnswerResult{Position: answer.Position}
+
+		if choices, ok := choiceCounts[answer.Position]; ok && (answer.Choice < 0 || int(answer.Choice) >= choices) {
+			results[i].Status, results[i].Error = statusError, "choice out of range"
+			continue
+		}
+
+		result, err := applyQuizAnswer(ctx, tx, query, userID, req.QuizID, answer)
+		if err != nil {
+			log.Printf("quiz %d answer %d: %v", req.QuizID, answer.Position, err)
+			results[i].Status, results[i].Error = statusError, "Error recording answer"
+			continue
+		}
+		results[i] = result
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(results); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// applyQuizAnswer grades one answer inside its own savepoint. A question that was
+// already answered (or doesn't exist) is reported as a duplicate and not graded again
+func applyQuizAnswer(ctx context.Context, tx pgx.Tx, query *db.Queries, userID, quizID int32, answer QuizAnswer) (result QuizAnswerResult, err error) {
+	result.Position = answer.Position
+
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer sp.Rollback(ctx)
+
+	qsp := query.WithTx(sp)
+
+	question, err := qsp.AnswerQuizQuestion(ctx, db.AnswerQuizQuestionParams{
+		ChosenIndex: pgtype.Int4{Int32: answer.Choice, Valid: true},
+		QuizID:      quizID,
+		Position:    answer.Position,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			result.Status = statusDuplicate
+			return result, nil
+		}
+		return result, err
+	}
+
+	result.Correct = answer.Choice == question.AnswerIndex
+	result.Answer = question.AnswerIndex
+
+	grade := scheduler.Again
+	if result.Correct {
+		grade = scheduler.Good
+	}
+
+	err = reviewCard(ctx, qsp, userID, ReviewRequest{
+		CardID:     question.CardID,
+		Grade:      grade.String(),
+		ResponseMs: answer.ResponseMs,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if err = sp.Commit(ctx); err != nil {
+		return result, err
+	}
+
+	result.Status = statusApplied
+	return result, nil
+}
@@ -0,0 +1,410 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/xlsx"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	formatCSV  string = "csv"
+	formatJSON string = "json"
+	formatXLSX string = "xlsx"
+)
+
+// class -> student -> set breakdown of cards studied, mastered, accuracy, last studied and
+// time on task. Teachers see every student or just the one in student_id; students only
+// ever get their own. format: csv returns the same report as a spreadsheet, one row per set
+func (h *DBHandler) GetClassProgressReport(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/classes/progress -H "id: 1" -H "student_id: 3" -H "format: csv"
+
+	reportFormat, err := getReportFormat(r, formatJSON, formatCSV)
+	if err != nil {
+		logAndSendError(w, err, "Invalid format", http.StatusBadRequest)
+		return
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var studentID pgtype.Int4
+	if vals, err := getHeaderVals(r, student_id); err == nil {
+		studentID.Int32, err = getInt32Id(vals[student_id])
+		if err != nil {
+			logAndSendError(w, err, "Invalid student id", http.StatusBadRequest)
+			return
+		}
+		studentID.Valid = true
+	}
+
+	if role != teacher {
+		if role != student || (studentID.Valid && studentID.Int32 != userID) {
+			logAndSendError(w, errors.New("forbidden"), "not a teacher", http.StatusUnauthorized)
+			return
+		}
+		studentID = pgtype.Int4{Int32: userID, Valid: true}
+	}
+
+	rows, err := query.GetClassProgressReport(ctx, db.GetClassProgressReportParams{
+		ClassID:   classID,
+		StudentID: studentID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error getting progress", http.StatusInternalServerError)
+		return
+	}
+
+	if reportFormat == formatCSV {
+		records := [][]string{{
+			"user_id", "username", "first_name", "last_name", "set_id", "set_name", "total_cards",
+			"cards_studied", "cards_mastered", "accuracy_pct", "attempts", "last_studied_at", "time_on_task_min",
+		}}
+		for _, row := range rows {
+			stats := newProgressStats(row)
+			records = append(records, []string{
+				strconv.Itoa(int(row.UserID)), row.Username, row.FirstName, row.LastName,
+				strconv.Itoa(int(row.SetID)), row.SetName,
+				strconv.Itoa(int(stats.TotalCards)), strconv.Itoa(int(stats.CardsStudied)), strconv.Itoa(int(stats.CardsMastered)),
+				strconv.Itoa(int(stats.AccuracyPct)), strconv.Itoa(int(stats.Attempts)),
+				formatReportTime(stats.LastStudiedAt), fmt.Sprintf("%.1f", float64(stats.TimeMs)/60000),
+			})
+		}
+		writeCSV(w, fmt.Sprintf("class-%d-progress.csv", classID), records)
+		return
+	}
+
+	// rows come ordered by student, so each student's sets are consecutive
+	students := []StudentProgress{}
+	for _, row := range rows {
+		n := len(students)
+		if n == 0 || students[n-1].UserID != row.UserID {
+			students = append(students, StudentProgress{
+				UserID:    row.UserID,
+				FirstName: row.FirstName,
+				LastName:  row.LastName,
+				Username:  row.Username,
+				Sets:      []SetProgress{},
+			})
+			n++
+		}
+
+		stats := newProgressStats(row)
+		students[n-1].Sets = append(students[n-1].Sets, SetProgress{
+			SetID:         row.SetID,
+			SetName:       row.SetName,
+			ProgressStats: stats,
+		})
+		students[n-1].ProgressStats.add(stats)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(students); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// item analysis across the class's students, hardest cards first so teachers know what
+// to re-teach. count limits the list
+func (h *DBHandler) ListHardestCardsInAClass(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/classes/hardest_cards -H "id: 1" -H "count: 20"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var limit pgtype.Int4
+	if vals, err := getHeaderVals(r, count); err == nil {
+		n, err := strconv.ParseInt(vals[count], 10, 32)
+		if err != nil || n < 1 {
+			logAndSendError(w, errHeader, "Count must be a positive number", http.StatusBadRequest)
+			return
+		}
+		limit = pgtype.Int4{Int32: int32(n), Valid: true}
+	}
+
+	rows, err := query.ListHardestCardsInAClass(ctx, db.ListHardestCardsInAClassParams{
+		ClassID:  classID,
+		MaxCards: limit,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error getting cards", http.StatusInternalServerError)
+		return
+	}
+
+	cards := make([]CardDifficulty, len(rows))
+	for i, row := range rows {
+		cards[i] = CardDifficulty{
+			ListHardestCardsInAClassRow: row,
+			AccuracyPct:                 accuracyPct(row.Correct, row.Attempts),
+		}
+		if row.ClassStudents > 0 {
+			cards[i].StruggledPct = row.StudentsStruggled * 100 / row.ClassStudents
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(cards); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// one row per student and a score, mastery % and completion column per assignment, plus
+// totals, as csv or an xlsx workbook for LMS imports
+func (h *DBHandler) GetClassGradebook(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/classes/gradebook -H "id: 1" -H "format: xlsx" -o gradebook.xlsx
+
+	reportFormat, err := getReportFormat(r, formatCSV, formatXLSX)
+	if err != nil {
+		logAndSendError(w, err, "Invalid format", http.StatusBadRequest)
+		return
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	class, err := query.GetClassById(ctx, classID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting class", http.StatusInternalServerError)
+		return
+	}
+
+	students, err := query.ListStudentsOfAClass(ctx, classID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting students", http.StatusInternalServerError)
+		return
+	}
+
+	assignments, err := query.ListAssignmentsInAClass(ctx, classID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting assignments", http.StatusInternalServerError)
+		return
+	}
+
+	grades, err := query.GetClassGradebook(ctx, classID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting gradebook", http.StatusInternalServerError)
+		return
+	}
+
+	type cell struct{ userID, assignmentID int32 }
+	gradeOf := make(map[cell]db.GetClassGradebookRow, len(grades))
+	for _, grade := range grades {
+		gradeOf[cell{grade.UserID, grade.AssignmentID}] = grade
+	}
+
+	header := []any{"user_id", "username", "first_name", "last_name"}
+	for _, a := range assignments {
+		label := fmt.Sprintf("%s (due %s)", a.SetName, a.DueAt.Time.Format("2006-01-02"))
+		header = append(header, label+" score", label+" mastery %", label+" complete")
+	}
+	header = append(header, "total score", "total mastery %", fmt.Sprintf("completed (of %d)", len(assignments)))
+
+	table := [][]any{header}
+	for _, s := range students {
+		row := []any{s.UserID, s.Username, s.FirstName, s.LastName}
+
+		var score, mastered, total, completed int32
+		for _, a := range assignments {
+			grade := gradeOf[cell{s.UserID, a.ID}]
+			pct := masteryPct(grade.MasteredCards, grade.TotalCards)
+
+			complete := "no"
+			if assignmentComplete(pct, grade.Reviews, a.RequiredMasteryPct, a.MinReviews) {
+				complete = "yes"
+				completed++
+			}
+			row = append(row, grade.Score, pct, complete)
+
+			score += grade.Score
+			mastered += grade.MasteredCards
+			total += grade.TotalCards
+		}
+
+		table = append(table, append(row, score, masteryPct(mastered, total), completed))
+	}
+
+	filename := fmt.Sprintf("class-%d-gradebook.%s", classID, reportFormat)
+
+	if reportFormat == formatXLSX {
+		// build it first so a failure can still be sent as an error response
+		var buf bytes.Buffer
+		if err := xlsx.Write(&buf, class.ClassName, table); err != nil {
+			logAndSendError(w, err, "Error writing xlsx", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Write(buf.Bytes())
+		return
+	}
+
+	records := make([][]string, len(table))
+	for i, row := range table {
+		records[i] = make([]string, len(row))
+		for j, value := range row {
+			records[i][j] = fmt.Sprint(value)
+		}
+	}
+	writeCSV(w, filename, records)
+}
+
+func newProgressStats(row db.GetClassProgressReportRow) ProgressStats {
+	stats := ProgressStats{
+		TotalCards:    row.TotalCards,
+		CardsStudied:  row.CardsStudied,
+		CardsMastered: row.CardsMastered,
+		Correct:       row.Correct,
+		Attempts:      row.Attempts,
+		LastStudiedAt: row.LastStudiedAt,
+		TimeMs:        row.TimeMs,
+	}
+	stats.AccuracyPct = accuracyPct(stats.Correct, stats.Attempts)
+	return stats
+}
+
+// add folds one set's stats into a student's totals
+func (p *ProgressStats) add(set ProgressStats) {
+	p.TotalCards += set.TotalCards
+	p.CardsStudied += set.CardsStudied
+	p.CardsMastered += set.CardsMastered
+	p.Correct += set.Correct
+	p.Attempts += set.Attempts
+	p.TimeMs += set.TimeMs
+	p.AccuracyPct = accuracyPct(p.Correct, p.Attempts)
+	if set.LastStudiedAt.Valid && (!p.LastStudiedAt.Valid || set.LastStudiedAt.Time.After(p.LastStudiedAt.Time)) {
+		p.LastStudiedAt = set.LastStudiedAt
+	}
+}
+
+func accuracyPct(correct, attempts int32) int32 {
+	if attempts == 0 {
+		return 0
+	}
+	return correct * 100 / attempts
+}
+
+// getReportFormat reads the optional format header; the first allowed format is the default
+func getReportFormat(r *http.Request, allowed ...string) (string, error) {
+	vals, err := getHeaderVals(r, format)
+	if err != nil {
+		return allowed[0], nil
+	}
+
+	if !slices.Contains(allowed, vals[format]) {
+		return "", fmt.Errorf("format must be one of %s", strings.Join(allowed, ", "))
+	}
+	return vals[format], nil
+}
+
+func formatReportTime(t pgtype.Timestamp) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.Format("2006-01-02 15:04")
+}
+
+// writeCSV sends records as a download named filename
+func writeCSV(w http.ResponseWriter, filename string, records [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	for _, record := range records {
+		for i, cell := range record {
+			record[i] = escapeCSVCell(cell)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(records); err != nil {
+		logAndSendError(w, err, "Error writing csv", http.StatusInternalServerError)
+	}
+}
+
+// spreadsheet apps treat a cell starting with one of these as a formula
+const csvFormulaChars string = "=+-@\t\r"
+
+// escapeCSVCell puts a ' in front of text that would open as a formula, so a card or a
+// student's name can't run anything on the teacher's machine. Plain numbers are left alone
+func escapeCSVCell(s string) string {
+	if s == "" || !strings.ContainsRune(csvFormulaChars, rune(s[0])) {
+		return s
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return s
+	}
+	return "'" + s
+}
+
+// unescapeCSVCell undoes escapeCSVCell so exported sets import unchanged
+func unescapeCSVCell(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(csvFormulaChars, rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
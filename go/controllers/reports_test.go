@@ -0,0 +1,31 @@
+package controllers
+
+import "testing"
+
+func TestEscapeCSVCell(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Ada Lovelace", "Ada Lovelace"},
+		{"", ""},
+		{"=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+		{"+cmd", "'+cmd"},
+		{"-ar verbs", "'-ar verbs"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1+1", "'\t=1+1"},
+		{"-3", "-3"},
+		{"+0.5", "+0.5"},
+		{"'quoted'", "'quoted'"},
+	}
+
+	for _, tt := range tests {
+		got := escapeCSVCell(tt.in)
+		if got != tt.want {
+			t.Errorf("escapeCSVCell(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if back := unescapeCSVCell(got); back != tt.in {
+			t.Errorf("unescapeCSVCell(%q) = %q, want %q", got, back, tt.in)
+		}
+	}
+}
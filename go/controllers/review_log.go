@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
+)
+
+func (h *DBHandler) ListReviewsOfACard(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/review_log/card -H "card_id: 1"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, card_id)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	cardID, err := getInt32Id(headerVals[card_id])
+	if err != nil {
+		logAndSendError(w, err, "Invalid card id", http.StatusBadRequest)
+		return
+	}
+
+	reviews, err := query.ListReviewsOfACard(ctx, db.ListReviewsOfACardParams{
+		UserID: userID,
+		CardID: cardID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error getting reviews", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(reviews); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+func (h *DBHandler) ListReviewsInASet(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/review_log/set -H "set_id: 1"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, set_id)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	setID, err := getInt32Id(headerVals[set_id])
+	if err != nil {
+		logAndSendError(w, err, "Invalid set id", http.StatusBadRequest)
+		return
+	}
+
+	// the reviews include the cards' sides
+	readable, err := canReadSet(ctx, query, r, setID, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to get flashcard set", http.StatusInternalServerError)
+		return
+	} else if !readable {
+		logAndSendError(w, errSetNotFound, "Flashcard set not found", http.StatusNotFound)
+		return
+	}
+
+	reviews, err := query.ListReviewsInASet(ctx, db.ListReviewsInASetParams{
+		UserID: userID,
+		SetID:  setID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error getting reviews", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(reviews); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+func (h *DBHandler) ListDailyReviews(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/review_log/daily
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	days, err := query.ListDailyReviews(ctx, userID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting reviews", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(days); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// teachers see any student's study days in their class, students only their own
+func (h *DBHandler) ListDailyReviewsInAClass(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/review_log/class/daily -H "id: 1" -H "student_id: 3"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, student_id)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	studentID, err := getInt32Id(headerVals[student_id])
+	if err != nil {
+		logAndSendError(w, err, "Invalid student id", http.StatusBadRequest)
+		return
+	}
+
+	if studentID != userID && role != teacher {
+		logAndSendError(w, errors.New("forbidden"), "not a teacher", http.StatusUnauthorized)
+		return
+	}
+
+	days, err := query.ListDailyReviewsInAClass(ctx, db.ListDailyReviewsInAClassParams{
+		UserID:  studentID,
+		ClassID: classID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error getting reviews", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(days); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,418 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
+)
+
+// a set and its cards at some point in its history
+type setSnapshot struct {
+	set   RevisionSet
+	cards map[int32]RevisionCard
+}
+
+func currentSnapshot(ctx context.Context, query *db.Queries, setID int32) (setSnapshot, error) {
+	flashcard_set, err := query.GetFlashcardSetById(ctx, setID)
+	if err != nil {
+		return setSnapshot{}, err
+	}
+
+	flashcards, err := query.ListFlashcardsOfASet(ctx, setID)
+	if err != nil {
+		return setSnapshot{}, err
+	}
+
+	s := setSnapshot{
+		set: RevisionSet{
+			SetName:             flashcard_set.SetName,
+			SetDescription:      flashcard_set.SetDescription,
+			MasteryStreak:       flashcard_set.MasteryStreak,
+			MasteryIntervalDays: flashcard_set.MasteryIntervalDays,
+			TypoTolerance:       flashcard_set.TypoTolerance,
+		},
+		cards: make(map[int32]RevisionCard, len(flashcards)),
+	}
+	for _, card := range flashcards {
+		s.cards[card.ID] = RevisionCard{Front: card.Front, Back: card.Back}
+	}
+	return s, nil
+}
+
+func (s setSnapshot) clone() setSnapshot {
+	c := setSnapshot{set: s.set, cards: make(map[int32]RevisionCard, len(s.cards))}
+	for id, card := range s.cards {
+		c.cards[id] = card
+	}
+	return c
+}
+
+// undo takes the snapshot back to before the revision. Revisions have to be undone newest first
+func (s *setSnapshot) undo(rev db.ListRevisionsAfterRow) error {
+	switch {
+	case !rev.CardID.Valid:
+		// nothing came before the set was created
+		if rev.Op == "update" {
+			return json.Unmarshal(rev.BeforeValues, &s.set)
+		}
+	case rev.Op == "create":
+		delete(s.cards, rev.CardID.Int32)
+	default:
+		var card RevisionCard
+		if err := json.Unmarshal(rev.BeforeValues, &card); err != nil {
+			return err
+		}
+		s.cards[rev.CardID.Int32] = card
+	}
+	return nil
+}
+
+// getRevisionID reads a revision id header and makes sure it's one of the set's
+func getRevisionID(ctx context.Context, query *db.Queries, r *http.Request, header string, setID int32) (int32, error) {
+	vals, err := getHeaderVals(r, header)
+	if err != nil {
+		return 0, err
+	}
+
+	revisionID, err := getInt32Id(vals[header])
+	if err != nil {
+		return 0, err
+	}
+
+	exists, err := query.RevisionExists(ctx, db.RevisionExistsParams{
+		SetID: setID,
+		ID:    revisionID,
+	})
+	if err != nil {
+		return 0, err
+	} else if !exists {
+		return 0, errors.New("no such revision in this set")
+	}
+	return revisionID, nil
+}
+
+// who changed what in the set and its cards, newest first
+func (h *DBHandler) ListSetRevisions(w http.ResponseWriter, r *http.Request) {
+	// curl localhost:8000/api/flashcards/sets/revisions -H "id: 1" -H "page: 1" -H "page_size: 50"
+
+	page, pageSize, err := getPage(r)
+	if err != nil {
+		logAndSendError(w, err, "Invalid page", http.StatusBadRequest)
+		return
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || (role != owner && role != user) {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	setID, ok := middleware.GetSetIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := query.ListRevisionsOfASet(ctx, db.ListRevisionsOfASetParams{
+		SetID:      setID,
+		PageOffset: int32((page - 1) * pageSize),
+		PageSize:   int32(pageSize + 1),
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error getting revisions", http.StatusInternalServerError)
+		return
+	}
+
+	res := RevisionsResponse{
+		Page:      page,
+		PageSize:  pageSize,
+		HasMore:   len(rows) > pageSize,
+		Revisions: make([]Revision, min(len(rows), pageSize)),
+	}
+	for i, row := range rows[:len(res.Revisions)] {
+		res.Revisions[i] = Revision{
+			ID:        row.ID,
+			CardID:    row.CardID.Int32,
+			UserID:    row.UserID.Int32,
+			Username:  row.Username,
+			Op:        row.Op,
+			Before:    row.BeforeValues,
+			After:     row.AfterValues,
+			CreatedAt: row.CreatedAt.Time,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// compares the set as it was after the from revision with how it was after the to revision,
+// or with how it is now when to isn't sent
+func (h *DBHandler) DiffSetRevisions(w http.ResponseWriter, r *http.Request) {
+	// curl localhost:8000/api/flashcards/sets/revisions/diff -H "id: 1" -H "from: 12" -H "to: 20"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || (role != owner && role != user) {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	setID, ok := middleware.GetSetIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	from, err := getRevisionID(ctx, query, r, fromStr, setID)
+	if err != nil {
+		logAndSendError(w, err, "Invalid from", http.StatusBadRequest)
+		return
+	}
+
+	var to int32
+	if _, err := getHeaderVals(r, toStr); err == nil {
+		to, err = getRevisionID(ctx, query, r, toStr, setID)
+		if err != nil {
+			logAndSendError(w, err, "Invalid to", http.StatusBadRequest)
+			return
+		}
+		if to <= from {
+			logAndSendError(w, errHeader, "to must be a later revision than from", http.StatusBadRequest)
+			return
+		}
+	}
+
+	current, err := currentSnapshot(ctx, query, setID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to get flashcard set", http.StatusInternalServerError)
+		return
+	}
+
+	revs, err := query.ListRevisionsAfter(ctx, db.ListRevisionsAfterParams{
+		SetID:      setID,
+		RevisionID: from,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error getting revisions", http.StatusInternalServerError)
+		return
+	}
+
+	// walk back from now, keeping a copy on reaching to. to is after from so it's always reached
+	before, after := current.clone(), current
+	for _, rev := range revs {
+		if rev.ID == to {
+			after = before.clone()
+		}
+		if err = before.undo(rev); err != nil {
+			logAndSendError(w, err, "Error reading revisions", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	res := RevisionDiffResponse{
+		From:    from,
+		To:      to,
+		Added:   []RevisionCardDiff{},
+		Changed: []RevisionCardDiff{},
+		Removed: []RevisionCardDiff{},
+	}
+	if before.set != after.set {
+		res.SetBefore, res.SetAfter = &before.set, &after.set
+	}
+	for id, card := range after.cards {
+		old, ok := before.cards[id]
+		if !ok {
+			res.Added = append(res.Added, RevisionCardDiff{CardID: id, After: &card})
+		} else if old != card {
+			res.Changed = append(res.Changed, RevisionCardDiff{CardID: id, Before: &old, After: &card})
+		}
+	}
+	for id, card := range before.cards {
+		if _, ok := after.cards[id]; !ok {
+			res.Removed = append(res.Removed, RevisionCardDiff{CardID: id, Before: &card})
+		}
+	}
+	for _, cards := range [][]RevisionCardDiff{res.Added, res.Changed, res.Removed} {
+		sort.Slice(cards, func(i, j int) bool { return cards[i].CardID < cards[j].CardID })
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// puts the set and its cards back the way they were after the given revision. Cards
+// added since are deleted and deleted ones come back under their old ids, without the
+// study history they had. The rollback is logged like any other edit, so it can be undone
+func (h *DBHandler) RollbackSet(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/flashcards/sets/revisions/rollback -H "set_id: 1" -H "revision_id: 12"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != owner {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	setID, ok := middleware.GetSetIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	revisionID, err := getRevisionID(ctx, qtx, r, revision_id, setID)
+	if err != nil {
+		logAndSendError(w, err, "Invalid revision_id", http.StatusBadRequest)
+		return
+	}
+
+	err = qtx.SetRevisionAuthor(ctx, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to set revision author", http.StatusInternalServerError)
+		return
+	}
+
+	current, err := currentSnapshot(ctx, qtx, setID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to get flashcard set", http.StatusInternalServerError)
+		return
+	}
+
+	revs, err := qtx.ListRevisionsAfter(ctx, db.ListRevisionsAfterParams{
+		SetID:      setID,
+		RevisionID: revisionID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error getting revisions", http.StatusInternalServerError)
+		return
+	}
+
+	target := current.clone()
+	for _, rev := range revs {
+		if err = target.undo(rev); err != nil {
+			logAndSendError(w, err, "Error reading revisions", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	res := RollbackResponse{RevisionID: revisionID}
+
+	for id := range current.cards {
+		if _, ok := target.cards[id]; ok {
+			continue
+		}
+		if err = qtx.DeleteFlashcard(ctx, id); err != nil {
+			logAndSendError(w, err, "Failed to delete flashcard", http.StatusInternalServerError)
+			return
+		}
+		res.Removed++
+	}
+
+	for id, card := range target.cards {
+		if now, ok := current.cards[id]; ok && now == card {
+			continue
+		}
+		n, err := qtx.RestoreFlashcard(ctx, db.RestoreFlashcardParams{
+			ID:    id,
+			Front: card.Front,
+			Back:  card.Back,
+			SetID: setID,
+		})
+		if err != nil {
+			logAndSendError(w, err, "Failed to restore flashcard", http.StatusInternalServerError)
+			return
+		}
+		if n == 0 {
+			res.Skipped++
+		} else {
+			res.Restored++
+		}
+	}
+
+	if target.set != current.set {
+		err = qtx.RestoreFlashcardSet(ctx, db.RestoreFlashcardSetParams{
+			SetName:             target.set.SetName,
+			SetDescription:      target.set.SetDescription,
+			MasteryStreak:       target.set.MasteryStreak,
+			MasteryIntervalDays: target.set.MasteryIntervalDays,
+			TypoTolerance:       target.set.TypoTolerance,
+			ID:                  setID,
+		})
+		if err != nil {
+			logAndSendError(w, err, "Failed to restore flashcard set", http.StatusInternalServerError)
+			return
+		}
+		res.SetRestored = true
+	}
+
+	// same as UpdateFlashcardSetMasteryRule: a fully specified rule is settled for everyone
+	// now, an inherited one on each student's next review
+	rule := target.set
+	ruleChanged := rule.MasteryStreak != current.set.MasteryStreak || rule.MasteryIntervalDays != current.set.MasteryIntervalDays
+	if ruleChanged && rule.MasteryStreak.Valid && rule.MasteryIntervalDays.Valid {
+		err = qtx.RecomputeMasteryInASet(ctx, db.RecomputeMasteryInASetParams{
+			Streak:       rule.MasteryStreak.Int32,
+			IntervalDays: rule.MasteryIntervalDays.Int32,
+			SetID:        setID,
+		})
+		if err != nil {
+			logAndSendError(w, err, "Failed to recompute mastery", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,415 @@
+package controllers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	maxRosterRows  int   = 500
+	maxRosterBytes int64 = 1 << 20
+
+	// stored as the password of roster accounts until the student sets one. It isn't a
+	// bcrypt hash, so no password ever matches it
+	unusablePassword string = "!"
+)
+
+// one parsed line of the roster csv
+type rosterRow struct {
+	line     int
+	name     string
+	email    string
+	username string
+}
+
+// a created account waiting for its welcome email, sent once the import commits
+type rosterWelcome struct {
+	email    string
+	username string
+	token    string
+}
+
+// the body is a csv of name,email[,username] with an optional header row. Every row is
+// imported in its own savepoint so one bad line doesn't stop the rest. With dry_run every
+// row is only checked and nothing is written, so the report shows what would happen.
+// Usernames are only reported for accounts the import creates. Welcome emails go out in the
+// background after the response, a student whose email fails can use Forgot Password
+func (h *DBHandler) ImportRoster(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/classes/roster -H "id: 1" -H "dry_run: true" -H "Content-Type: text/csv" --data-binary @roster.csv
+
+	rows, err := readRosterCSV(http.MaxBytesReader(w, r.Body, maxRosterBytes))
+	if err != nil {
+		logAndSendError(w, err, "Invalid roster: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dryRun := false
+	if vals, err := getHeaderVals(r, dry_run); err == nil {
+		dryRun, err = strconv.ParseBool(vals[dry_run])
+		if err != nil {
+			logAndSendError(w, err, "Invalid dry_run", http.StatusBadRequest)
+			return
+		}
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	class, err := query.GetClassById(ctx, classID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting class", http.StatusInternalServerError)
+		return
+	}
+
+	var tx pgx.Tx
+	if !dryRun {
+		tx, err = conn.Begin(ctx)
+		if err != nil {
+			logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback(ctx)
+	}
+
+	res := RosterImportResponse{
+		DryRun: dryRun,
+		Rows:   make([]RosterImportResult, len(rows)),
+	}
+
+	// usernames and emails claimed earlier in this file, which the database can't see yet in a dry run
+	usernames := make(map[string]bool)
+	emails := make(map[string]bool)
+	var welcomes []rosterWelcome
+
+	for i, row := range rows {
+		res.Rows[i] = RosterImportResult{Row: row.line, Email: row.email}
+
+		if emails[row.email] {
+			res.Rows[i].Status, res.Rows[i].Detail = statusSkipped, "duplicate row"
+			continue
+		}
+		emails[row.email] = true
+
+		var result RosterImportResult
+		var setupToken string
+		if dryRun {
+			result, _, err = checkRosterRow(ctx, query, classID, row, usernames)
+			if err == nil && result.Status == statusCreated {
+				usernames[result.Username] = true
+			}
+		} else {
+			result, setupToken, err = importRosterRow(ctx, tx, query, classID, row, usernames)
+		}
+		if err != nil {
+			log.Printf("roster import class %d line %d: %v", classID, row.line, err)
+			res.Rows[i].Status, res.Rows[i].Detail = statusError, "Error importing row"
+			continue
+		}
+		res.Rows[i] = result
+
+		if setupToken != "" {
+			welcomes = append(welcomes, rosterWelcome{
+				email:    row.email,
+				username: result.Username,
+				token:    setupToken,
+			})
+		}
+	}
+
+	if !dryRun {
+		err = tx.Commit(ctx)
+		if err != nil {
+			logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+			return
+		}
+
+		// one smtp round trip per student would outlast the server's write timeout
+		go sendRosterWelcomes(class.ClassName, welcomes)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// checkRosterRow works out what importing the row would do without writing anything: skip
+// it, enroll an existing account (whose id is returned) or create one under result.Username.
+// An existing account's username is left out, the teacher only knows its email.
+// Problems with the row itself are reported in the result; err is only for database failures
+func checkRosterRow(ctx context.Context, query *db.Queries, classID int32, row rosterRow, usernames map[string]bool) (result RosterImportResult, userID int32, err error) {
+	result = RosterImportResult{Row: row.line, Email: row.email}
+
+	existing, err := query.GetUserByEmail(ctx, row.email)
+	switch {
+	case err == nil:
+		_, err = query.VerifyClassMember(ctx, db.VerifyClassMemberParams{
+			ClassID: classID,
+			UserID:  existing.ID,
+		})
+		if err == nil {
+			result.Status, result.Detail = statusSkipped, "already in class"
+			return result, 0, nil
+		} else if !strings.Contains(err.Error(), "no rows") {
+			return result, 0, err
+		}
+		result.Status = statusEnrolled
+		return result, existing.ID, nil
+
+	case strings.Contains(err.Error(), "no rows"):
+		if row.name == "" {
+			result.Status, result.Detail = statusError, "name is required for new accounts"
+			return result, 0, nil
+		}
+
+		result.Username, err = pickUsername(ctx, query, row, usernames)
+		if err != nil {
+			if errors.Is(err, errUsernameTaken) {
+				result.Status, result.Detail = statusError, err.Error()
+				return result, 0, nil
+			}
+			return result, 0, err
+		}
+		result.Status = statusCreated
+		return result, 0, nil
+
+	default:
+		return result, 0, err
+	}
+}
+
+// importRosterRow creates the account if the email is new and enrolls it as a student.
+// Problems with the row itself are reported in the result; err is only for database failures.
+// The password setup token is returned for new accounts
+func importRosterRow(ctx context.Context, tx pgx.Tx, query *db.Queries, classID int32, row rosterRow, usernames map[string]bool) (result RosterImportResult, setupToken string, err error) {
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return RosterImportResult{Row: row.line, Email: row.email}, "", err
+	}
+	defer sp.Rollback(ctx)
+
+	qsp := query.WithTx(sp)
+
+	result, userID, err := checkRosterRow(ctx, qsp, classID, row, usernames)
+	if err != nil || (result.Status != statusCreated && result.Status != statusEnrolled) {
+		return result, "", err
+	}
+
+	if result.Status == statusCreated {
+		// the student sets their own password with the token
+		first, last := splitName(row.name)
+		created, err := qsp.CreateUser(ctx, db.CreateUserParams{
+			Username:  result.Username,
+			FirstName: first,
+			LastName:  last,
+			Email:     row.email,
+			Password:  unusablePassword,
+		})
+		if err != nil {
+			return result, "", err
+		}
+		userID = created.ID
+
+		setupToken, err = generateUniqueToken()
+		if err != nil {
+			return result, "", err
+		}
+		err = qsp.CreateResetToken(ctx, db.CreateResetTokenParams{
+			ResetToken: pgtype.Text{String: setupToken, Valid: true},
+			ID:         userID,
+		})
+		if err != nil {
+			return result, "", err
+		}
+	}
+
+	err = qsp.JoinClass(ctx, db.JoinClassParams{
+		UserID:  userID,
+		ClassID: classID,
+		Role:    student,
+	})
+	if err != nil {
+		return result, "", err
+	}
+
+	if err = sp.Commit(ctx); err != nil {
+		return result, "", err
+	}
+
+	if result.Status == statusCreated {
+		usernames[result.Username] = true
+	}
+	return result, setupToken, nil
+}
+
+// pickUsername uses the requested username or, without one, the part of the email before
+// the @ with a number added until it's free
+func pickUsername(ctx context.Context, query *db.Queries, row rosterRow, usernames map[string]bool) (string, error) {
+	free := func(name string) (bool, error) {
+		if usernames[name] {
+			return false, nil
+		}
+		_, err := query.GetUserByUsername(ctx, name)
+		if err == nil {
+			return false, nil
+		} else if strings.Contains(err.Error(), "no rows") {
+			return true, nil
+		}
+		return false, err
+	}
+
+	if row.username != "" {
+		ok, err := free(row.username)
+		if err != nil {
+			return "", err
+		} else if !ok {
+			return "", errUsernameTaken
+		}
+		return row.username, nil
+	}
+
+	base := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		}
+		return -1
+	}, strings.ToLower(row.email[:strings.LastIndex(row.email, "@")]))
+	if base == "" {
+		base = student
+	}
+
+	for n := 1; n < 1000; n++ {
+		name := base
+		if n > 1 {
+			name = fmt.Sprintf("%s%d", base, n)
+		}
+		ok, err := free(name)
+		if err != nil {
+			return "", err
+		} else if ok {
+			return name, nil
+		}
+	}
+	return "", errUsernameTaken
+}
+
+// readRosterCSV parses and validates the whole file up front so a malformed upload
+// is rejected before anything is written
+func readRosterCSV(body io.Reader) ([]rosterRow, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows []rosterRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		if len(rows) == 0 && len(record) >= 2 &&
+			strings.EqualFold(strings.TrimSpace(record[0]), "name") && strings.EqualFold(strings.TrimSpace(record[1]), email) {
+			continue
+		}
+
+		if len(record) < 2 || len(record) > 3 {
+			return nil, fmt.Errorf("line %d: expected name,email[,username]", line)
+		}
+
+		addr, err := mail.ParseAddress(strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid email %q", line, record[1])
+		}
+
+		row := rosterRow{
+			line:  line,
+			name:  strings.Join(strings.Fields(record[0]), " "),
+			email: addr.Address,
+		}
+		if len(record) == 3 {
+			row.username = strings.TrimSpace(record[2])
+		}
+
+		rows = append(rows, row)
+		if len(rows) > maxRosterRows {
+			return nil, fmt.Errorf("more than %d rows", maxRosterRows)
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("empty file")
+	}
+	return rows, nil
+}
+
+// splitName accepts "First Last" or "Last, First"
+func splitName(name string) (first, last string) {
+	if before, after, ok := strings.Cut(name, ","); ok {
+		return strings.TrimSpace(after), strings.TrimSpace(before)
+	}
+	if i := strings.LastIndex(name, " "); i > 0 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+// sendRosterWelcomes emails each new account its username and password setup token. Failures
+// are only logged, the import has already been reported
+func sendRosterWelcomes(className string, welcomes []rosterWelcome) {
+	for _, welcome := range welcomes {
+		if err := sendMail(welcome.email, "Welcome to Cowboy Cards", rosterWelcomeBody(className, welcome)); err != nil {
+			log.Printf("roster import welcome email to %s: %v", welcome.email, err)
+		}
+	}
+}
+
+func rosterWelcomeBody(className string, welcome rosterWelcome) string {
+	return fmt.Sprintf(`
+Howdy Partner!
+
+Your teacher has added you to %s on Cowboy Cards. Your username is %s.
+
+To set your password, reset it with this email address and the following token:
+%s
+
+This token will expire in 1 hour. If it does, just use Forgot Password to get a new one.
+
+Yeehaw!
+The Cowboy Cards Team
+	`, className, welcome.username, welcome.token)
+}
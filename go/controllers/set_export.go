@@ -0,0 +1,381 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// bundles from a newer version are refused rather than half imported
+const bundleVersion int = 1
+
+// links to media files in a card's text, which is all the media a card has
+var mediaURL = regexp.MustCompile(`(?i)https?://\S+?\.(png|jpe?g|gif|webp|svg|mp3|wav|ogg|m4a|mp4|webm)(\?\S*)?(\s|$)`)
+
+// text is Quizlet's "term<tab>definition" per line, separator and card_separator change the
+// defaults like they do on import. A side containing a separator has it replaced by a space
+// so the export can always be read back
+func (h *DBHandler) ExportFlashcardSet(w http.ResponseWriter, r *http.Request) {
+	// curl localhost:8000/api/flashcards/sets/export -H "id: 1" -H "format: json" -o set.json
+
+	exportFormat, err := getReportFormat(r, formatText, formatCSV, formatJSON)
+	if err != nil {
+		logAndSendError(w, err, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	termSep, cardSep := "\t", "\n"
+	if exportFormat == formatText {
+		termSep, cardSep, err = getImportSeparators(r, formatText)
+		if err != nil {
+			logAndSendError(w, err, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	setID, ok := middleware.GetSetIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	readable, err := canReadSet(ctx, query, r, setID, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to get flashcard set", http.StatusInternalServerError)
+		return
+	} else if !readable {
+		logAndSendError(w, errSetNotFound, "Flashcard set not found", http.StatusNotFound)
+		return
+	}
+
+	flashcard_set, err := query.GetFlashcardSetById(ctx, setID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to get flashcard set", http.StatusInternalServerError)
+		return
+	}
+
+	flashcards, err := query.ListFlashcardsOfASet(ctx, setID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting flashcards from DB", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("set-%d", setID)
+
+	switch exportFormat {
+	case formatCSV:
+		records := [][]string{{front, back}}
+		for _, card := range flashcards {
+			records = append(records, []string{card.Front, card.Back})
+		}
+		writeCSV(w, filename+".csv", records)
+
+	case formatJSON:
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".json"))
+		if err := json.NewEncoder(w).Encode(newSetBundle(flashcard_set, flashcards)); err != nil {
+			logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+		}
+
+	default:
+		clean := strings.NewReplacer(termSep, " ", cardSep, " ")
+		var sb strings.Builder
+		for _, card := range flashcards {
+			sb.WriteString(clean.Replace(card.Front) + termSep + clean.Replace(card.Back) + cardSep)
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".txt"))
+		w.Write([]byte(sb.String()))
+	}
+}
+
+// the body is a bundle from ExportFlashcardSet, here or on another instance. It becomes a
+// new set owned by the caller with the bundle's settings. Media references that aren't
+// already in a card's text are added to the end of that side so the links aren't lost
+func (h *DBHandler) ImportSetBundle(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/flashcards/sets/bundle --data-binary @set.json
+
+	var bundle SetBundle
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxImportBytes)).Decode(&bundle); err != nil {
+		logAndSendError(w, err, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := checkBundle(&bundle); err != nil {
+		logAndSendError(w, err, "Invalid bundle: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Database connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	err = qtx.SetRevisionAuthor(ctx, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to set revision author", http.StatusInternalServerError)
+		return
+	}
+
+	flashcard_set, err := qtx.CreateFlashcardSet(ctx, db.CreateFlashcardSetParams{
+		SetName:        bundle.Set.Name,
+		SetDescription: bundle.Set.Description,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to create flashcard set", http.StatusInternalServerError)
+		return
+	}
+
+	err = qtx.JoinSet(ctx, db.JoinSetParams{
+		UserID: userID,
+		SetID:  flashcard_set.ID,
+		Role:   owner,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error adding set", http.StatusInternalServerError)
+		return
+	}
+
+	err = applyBundleSettings(ctx, qtx, flashcard_set.ID, bundle.Set)
+	if err != nil {
+		logAndSendError(w, err, "Failed to apply set settings", http.StatusInternalServerError)
+		return
+	}
+
+	for _, card := range bundle.Cards {
+		_, err = qtx.CreateFlashcard(ctx, db.CreateFlashcardParams{
+			Front: card.Front,
+			Back:  card.Back,
+			SetID: flashcard_set.ID,
+		})
+		if err != nil {
+			logAndSendError(w, err, "Failed to create flashcard", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// read back so the response has the settings and link token
+	flashcard_set, err = qtx.GetFlashcardSetById(ctx, flashcard_set.ID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to get flashcard set", http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(flashcard_set); err != nil {
+		logAndSendError(w, err, "Error encoding response", http.StatusInternalServerError)
+	}
+}
+
+// newSetBundle is the json export of a set, with each card's media links listed
+func newSetBundle(set db.FlashcardSet, flashcards []db.Flashcard) SetBundle {
+	bundle := SetBundle{
+		Version:    bundleVersion,
+		ExportedAt: time.Now().UTC(),
+		Set: BundleSet{
+			Name:                set.SetName,
+			Description:         set.SetDescription,
+			MasteryStreak:       int4Ptr(set.MasteryStreak),
+			MasteryIntervalDays: int4Ptr(set.MasteryIntervalDays),
+			TypoTolerance:       set.TypoTolerance,
+			Visibility:          set.Visibility,
+		},
+		Cards: make([]BundleCard, len(flashcards)),
+	}
+	for i, card := range flashcards {
+		bundle.Cards[i] = BundleCard{
+			Front: card.Front,
+			Back:  card.Back,
+			Media: append(findMedia(front, card.Front), findMedia(back, card.Back)...),
+		}
+	}
+	return bundle
+}
+
+// checkBundle validates the whole bundle before anything is written and fills in defaults.
+// Media links are merged into the card text here, so the limits hold for what gets saved
+func checkBundle(bundle *SetBundle) error {
+	if bundle.Version < 1 || bundle.Version > bundleVersion {
+		return fmt.Errorf("unsupported version %d, this server reads up to version %d", bundle.Version, bundleVersion)
+	}
+
+	bundle.Set.Name = strings.TrimSpace(bundle.Set.Name)
+	if bundle.Set.Name == "" {
+		return errors.New("set name is required")
+	}
+	if bundle.Set.Visibility == "" {
+		bundle.Set.Visibility = setClass
+	} else if !slices.Contains(setVisibilityLevels, bundle.Set.Visibility) {
+		return fmt.Errorf("visibility must be one of %s", strings.Join(setVisibilityLevels, ", "))
+	}
+	if bundle.Set.TypoTolerance < 0 {
+		return errors.New("typo_tolerance can't be negative")
+	}
+	for _, threshold := range []*int32{bundle.Set.MasteryStreak, bundle.Set.MasteryIntervalDays} {
+		if threshold != nil && *threshold < 0 {
+			return errors.New("mastery thresholds can't be negative")
+		}
+	}
+
+	if len(bundle.Cards) > maxImportCards {
+		return fmt.Errorf("more than %d cards", maxImportCards)
+	}
+	for i, card := range bundle.Cards {
+		row := newImportRow(i+1, []string{card.Front, card.Back})
+		if row.err != "" {
+			return fmt.Errorf("card %d: %s", i+1, row.err)
+		}
+		for _, media := range card.Media {
+			if media.Side != front && media.Side != back {
+				return fmt.Errorf("card %d: media side must be front or back", i+1)
+			}
+			if !strings.HasPrefix(media.URL, "http://") && !strings.HasPrefix(media.URL, "https://") {
+				return fmt.Errorf("card %d: media url must be http or https", i+1)
+			}
+			if utf8.RuneCountInString(media.URL) > maxCardSide {
+				return fmt.Errorf("card %d: media url is too long", i+1)
+			}
+		}
+
+		bundle.Cards[i].Front = withMedia(row.front, front, card.Media)
+		bundle.Cards[i].Back = withMedia(row.back, back, card.Media)
+		if utf8.RuneCountInString(bundle.Cards[i].Front) > maxCardSide || utf8.RuneCountInString(bundle.Cards[i].Back) > maxCardSide {
+			return fmt.Errorf("card %d: with its media links a side is over %d characters", i+1, maxCardSide)
+		}
+	}
+	return nil
+}
+
+// applyBundleSettings copies the exported settings onto the new set. Unlisted sets get a new
+// link token, the old one belongs to the other instance
+func applyBundleSettings(ctx context.Context, query *db.Queries, setID int32, set BundleSet) error {
+	err := query.UpdateFlashcardSetMasteryRule(ctx, db.UpdateFlashcardSetMasteryRuleParams{
+		MasteryStreak:       int4FromPtr(set.MasteryStreak),
+		MasteryIntervalDays: int4FromPtr(set.MasteryIntervalDays),
+		ID:                  setID,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = query.UpdateFlashcardSetTypoTolerance(ctx, db.UpdateFlashcardSetTypoToleranceParams{
+		TypoTolerance: set.TypoTolerance,
+		ID:            setID,
+	})
+	if err != nil {
+		return err
+	}
+
+	var shareToken pgtype.Text
+	if set.Visibility == setUnlisted {
+		newToken, err := generateUniqueToken()
+		if err != nil {
+			return err
+		}
+		shareToken = pgtype.Text{String: newToken, Valid: true}
+	}
+	_, err = query.UpdateFlashcardSetVisibility(ctx, db.UpdateFlashcardSetVisibilityParams{
+		Visibility: set.Visibility,
+		ShareToken: shareToken,
+		ID:         setID,
+	})
+	return err
+}
+
+// findMedia lists the media links in one side of a card
+func findMedia(side, text string) []BundleMedia {
+	var media []BundleMedia
+	for _, m := range mediaURL.FindAllStringSubmatch(text, -1) {
+		media = append(media, BundleMedia{
+			Side: side,
+			URL:  strings.TrimSpace(m[0]),
+			Type: mediaType(m[1]),
+		})
+	}
+	return media
+}
+
+func mediaType(ext string) string {
+	switch strings.ToLower(ext) {
+	case "mp3", "wav", "ogg", "m4a":
+		return "audio"
+	case "mp4", "webm":
+		return "video"
+	}
+	return "image"
+}
+
+// withMedia adds the side's media links that aren't in its text yet
+func withMedia(text, side string, media []BundleMedia) string {
+	for _, m := range media {
+		if m.Side == side && !strings.Contains(text, m.URL) {
+			text += "\n" + m.URL
+		}
+	}
+	return text
+}
+
+func int4Ptr(v pgtype.Int4) *int32 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int32
+}
+
+func int4FromPtr(v *int32) pgtype.Int4 {
+	if v == nil {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: *v, Valid: true}
+}
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestSetBundleRoundTrip(t *testing.T) {
+	set := db.FlashcardSet{
+		SetName:        "Spanish 1",
+		SetDescription: "Unit 1",
+		MasteryStreak:  pgtype.Int4{Int32: 4, Valid: true},
+		TypoTolerance:  2,
+		Visibility:     setUnlisted,
+	}
+	flashcards := []db.Flashcard{
+		{Front: "hola", Back: "hello"},
+		{Front: "el gato\nhttps://example.com/cat.png", Back: "the cat https://example.com/cat.mp3?v=2"},
+	}
+
+	data, err := json.Marshal(newSetBundle(set, flashcards))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bundle SetBundle
+	if err = json.Unmarshal(data, &bundle); err != nil {
+		t.Fatal(err)
+	}
+	if err = checkBundle(&bundle); err != nil {
+		t.Fatal(err)
+	}
+
+	wantSet := BundleSet{
+		Name:          set.SetName,
+		Description:   set.SetDescription,
+		MasteryStreak: &set.MasteryStreak.Int32,
+		TypoTolerance: set.TypoTolerance,
+		Visibility:    set.Visibility,
+	}
+	if !reflect.DeepEqual(bundle.Set, wantSet) {
+		t.Errorf("got set %+v, want %+v", bundle.Set, wantSet)
+	}
+
+	if len(bundle.Cards) != len(flashcards) {
+		t.Fatalf("got %d cards, want %d", len(bundle.Cards), len(flashcards))
+	}
+	for i, card := range bundle.Cards {
+		if card.Front != flashcards[i].Front || card.Back != flashcards[i].Back {
+			t.Errorf("card %d: got %q / %q, want %q / %q", i, card.Front, card.Back, flashcards[i].Front, flashcards[i].Back)
+		}
+	}
+	if len(bundle.Cards[1].Media) != 2 {
+		t.Errorf("got media %+v, want the image and the audio", bundle.Cards[1].Media)
+	}
+}
+
+func TestCheckBundle(t *testing.T) {
+	valid := func() SetBundle {
+		return SetBundle{
+			Version: bundleVersion,
+			Set:     BundleSet{Name: "  Spanish 1 "},
+			Cards:   []BundleCard{{Front: " hola ", Back: "hello"}},
+		}
+	}
+	negative := int32(-1)
+
+	tests := []struct {
+		name    string
+		edit    func(b *SetBundle)
+		wantErr string
+	}{
+		{"valid", func(b *SetBundle) {}, ""},
+		{"old version", func(b *SetBundle) { b.Version = 0 }, "unsupported version"},
+		{"newer version", func(b *SetBundle) { b.Version = bundleVersion + 1 }, "unsupported version"},
+		{"no name", func(b *SetBundle) { b.Set.Name = " " }, "set name is required"},
+		{"bad visibility", func(b *SetBundle) { b.Set.Visibility = "secret" }, "visibility must be one of"},
+		{"negative typo tolerance", func(b *SetBundle) { b.Set.TypoTolerance = -1 }, "typo_tolerance"},
+		{"negative mastery", func(b *SetBundle) { b.Set.MasteryIntervalDays = &negative }, "mastery thresholds"},
+		{"empty back", func(b *SetBundle) { b.Cards[0].Back = "" }, "card 1: definition is empty"},
+		{"too many cards", func(b *SetBundle) { b.Cards = make([]BundleCard, maxImportCards+1) }, "more than"},
+		{"media side", func(b *SetBundle) {
+			b.Cards[0].Media = []BundleMedia{{Side: "middle", URL: "https://example.com/a.png"}}
+		}, "media side"},
+		{"media scheme", func(b *SetBundle) {
+			b.Cards[0].Media = []BundleMedia{{Side: front, URL: "javascript:alert(1)"}}
+		}, "http or https"},
+		{"side over the limit once media is added", func(b *SetBundle) {
+			b.Cards[0].Front = strings.Repeat("a", maxCardSide-10)
+			b.Cards[0].Media = []BundleMedia{{Side: front, URL: "https://example.com/a.png"}}
+		}, "with its media links"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle := valid()
+			tt.edit(&bundle)
+
+			err := checkBundle(&bundle)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("got %v, want no error", err)
+				}
+				if bundle.Set.Name != "Spanish 1" || bundle.Set.Visibility != setClass || bundle.Cards[0].Front != "hola" {
+					t.Errorf("got %+v, want trimmed text and class visibility", bundle)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFindMedia(t *testing.T) {
+	tests := []struct {
+		text string
+		want []BundleMedia
+	}{
+		{"no links", nil},
+		{"a page https://example.com/page", nil},
+		{"look https://example.com/a.PNG here", []BundleMedia{{Side: front, URL: "https://example.com/a.PNG", Type: "image"}}},
+		{"http://example.com/s.mp3?v=2\nhttps://example.com/v.webm", []BundleMedia{
+			{Side: front, URL: "http://example.com/s.mp3?v=2", Type: "audio"},
+			{Side: front, URL: "https://example.com/v.webm", Type: "video"},
+		}},
+	}
+
+	for _, tt := range tests {
+		if got := findMedia(front, tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("findMedia(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWithMedia(t *testing.T) {
+	media := []BundleMedia{
+		{Side: front, URL: "https://example.com/a.png"},
+		{Side: back, URL: "https://example.com/b.mp3"},
+		{Side: front, URL: "https://example.com/c.gif"},
+	}
+
+	tests := []struct {
+		text string
+		side string
+		want string
+	}{
+		{"cat", front, "cat\nhttps://example.com/a.png\nhttps://example.com/c.gif"},
+		{"cat https://example.com/a.png", front, "cat https://example.com/a.png\nhttps://example.com/c.gif"},
+		{"gato", back, "gato\nhttps://example.com/b.mp3"},
+	}
+
+	for _, tt := range tests {
+		if got := withMedia(tt.text, tt.side, media); got != tt.want {
+			t.Errorf("withMedia(%q, %q) = %q, want %q", tt.text, tt.side, got, tt.want)
+		}
+	}
+}
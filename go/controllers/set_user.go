@@ -2,14 +2,18 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
 	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
 )
 
+// joining only ever makes a user, owners are added through GrantSetOwner
 func (h *DBHandler) JoinSet(w http.ResponseWriter, r *http.Request) {
-	// curl POST localhost:8000/api/class_set -H "id: 1" -H "set_id: 1"
+	// curl -X POST localhost:8000/api/set_user -H "set_id: 1" -H "token: share-token"
 
 	query, ctx, conn, err := getQueryConnAndContext(r, h)
 	if err != nil {
@@ -25,7 +29,7 @@ func (h *DBHandler) JoinSet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	headerVals, err := getHeaderVals(r, set_id, roleStr)
+	headerVals, err := getHeaderVals(r, set_id)
 	if err != nil {
 		logAndSendError(w, err, "Header error", http.StatusBadRequest)
 		return
@@ -37,12 +41,26 @@ func (h *DBHandler) JoinSet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// joining would make the set readable, so it has to be readable already
+	readable, err := canReadSet(ctx, query, r, setID, userID)
+	if err != nil {
+		logAndSendError(w, err, "Error adding set", http.StatusInternalServerError)
+		return
+	} else if !readable {
+		logAndSendError(w, errSetNotFound, "Flashcard set not found", http.StatusNotFound)
+		return
+	}
+
 	err = query.JoinSet(ctx, db.JoinSetParams{
 		UserID: userID,
 		SetID:  setID,
-		Role:   headerVals[roleStr],
+		Role:   user,
 	})
 	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			logAndSendError(w, err, "Already joined this set", http.StatusConflict)
+			return
+		}
 		logAndSendError(w, err, "Error adding set", http.StatusInternalServerError)
 		return
 	}
@@ -53,6 +71,115 @@ func (h *DBHandler) JoinSet(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// an owner makes another user of the set an owner; they have to have joined it first
+func (h *DBHandler) GrantSetOwner(w http.ResponseWriter, r *http.Request) {
+	// curl -X PUT localhost:8000/api/flashcards/sets/owner -H "id: 1" -H "user_id: 5"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != owner {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	setID, ok := middleware.GetSetIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, user_id)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	memberID, err := getInt32Id(headerVals[user_id])
+	if err != nil {
+		logAndSendError(w, err, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := query.PromoteSetMember(ctx, db.PromoteSetMemberParams{
+		SetID:  setID,
+		UserID: memberID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to grant owner", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		logAndSendError(w, errors.New("not a member"), "User has not joined this set", http.StatusNotFound)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode("Owner granted"); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// a private set still counts for the user, but its score is kept off the leaderboards
+// other students see
+func (h *DBHandler) UpdateSetUserPrivacy(w http.ResponseWriter, r *http.Request) {
+	// curl -X PUT localhost:8000/api/set_user/private -H "id: 1" -H "is_private: true"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	setID, ok := middleware.GetSetIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, is_private)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	private, err := strconv.ParseBool(headerVals[is_private])
+	if err != nil {
+		logAndSendError(w, err, "Invalid is_private", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := query.UpdateSetUserPrivacy(ctx, db.UpdateSetUserPrivacyParams{
+		IsPrivate: private,
+		SetID:     setID,
+		UserID:    userID,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Error updating set privacy", http.StatusInternalServerError)
+		return
+	}
+	if updated == 0 {
+		logAndSendError(w, errors.New("not a member"), "Join the set first", http.StatusNotFound)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(private); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
 func (h *DBHandler) LeaveSet(w http.ResponseWriter, r *http.Request) {
 	// curl -X DELETE localhost:8000/api/class_user/ -H "id: 1" -H "set_id"
 
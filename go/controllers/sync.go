@@ -0,0 +1,676 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Delta sync for the mobile app.
+//
+// Pull: a device sends the cursor from its last pull (0 the first time) and gets back the
+// current rows for everything that changed since, plus tombstones for deletes. A cursor of 0
+// returns a full snapshot. The device keeps a set while a set_user row of its user or a
+// class_set row of one of its classes points at it, and drops it otherwise. Private sets are
+// the exception: they're only synced to their own users, and a set going private reaches
+// everyone else as a flashcard_sets tombstone.
+//
+// The cursor is "<tx_id>:<id>" of the last change sent, see sync.sql for why changes are
+// ordered by transaction. The change log keeps syncRetentionDays of changes; a cursor older
+// than that gets 410 Gone and the device starts over from 0.
+//
+// Push: edits to sets and cards carry the updated_at the device last pulled. The first edit to
+// reach the server wins; a later edit against a stale updated_at is rejected as a conflict
+// and the winning copy is sent back so the device can rebase. Deleting a card someone else has
+// edited is a conflict too, and editing a card that is already deleted reports it gone.
+// Reviews are not pushed here, they go through /card_history/batch.
+//
+// A pushed create carries a client_id the device picks. The server remembers it for
+// syncRetentionDays, so a push retried after a dropped response gets back the card the first
+// one made rather than a second copy.
+
+const (
+	syncPageSize      int    = 1000
+	syncRetentionDays int    = 30
+	maxSyncPushBytes  int64  = 8 << 20
+	maxClientID       int    = 64
+	syncCreate        string = "create"
+	syncUpdate        string = "update"
+	syncDelete        string = "delete"
+)
+
+// ids to load for a pull, collected from the change log or the snapshot
+type syncKeys struct {
+	sets     []int32 // set rows only
+	fullSets []int32 // newly visible: set, all cards, set_user, card_history
+	cards    []int32
+	classes  []int32
+	history  []int32
+}
+
+func (h *DBHandler) PullChanges(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/sync -H "cursor: 0"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, cursor)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	since, err := parseSyncCursor(headerVals[cursor])
+	if err != nil {
+		logAndSendError(w, err, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	// one snapshot for the change log and the rows it points at
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	horizon, err := qtx.GetSyncHorizon(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Error getting cursor", http.StatusInternalServerError)
+		return
+	}
+
+	// caught up to the horizon unless a page runs out first
+	res := SyncResponse{Cursor: syncCursor{txID: max(horizon, since.txID)}.String()}
+	var keys syncKeys
+
+	if since == (syncCursor{}) {
+
+		keys.fullSets, err = qtx.ListVisibleSetIds(ctx, userID)
+		if err != nil {
+			logAndSendError(w, err, "Error getting sets", http.StatusInternalServerError)
+			return
+		}
+
+		classes, err := qtx.ListClassesOfAUser(ctx, userID)
+		if err != nil {
+			logAndSendError(w, err, "Error getting classes", http.StatusInternalServerError)
+			return
+		}
+		for _, class := range classes {
+			keys.classes = append(keys.classes, class.ClassID)
+		}
+	} else {
+		pruned, err := qtx.GetChangeLogPruned(ctx)
+		if err != nil {
+			logAndSendError(w, err, "Error getting cursor", http.StatusInternalServerError)
+			return
+		}
+		if since.txID <= pruned {
+			logAndSendError(w, errors.New("cursor expired"), "Cursor expired, pull again from 0", http.StatusGone)
+			return
+		}
+
+		changes, err := qtx.ListChangesForUser(ctx, db.ListChangesForUserParams{
+			CursorTxID: since.txID,
+			CursorID:   since.id,
+			Horizon:    horizon,
+			UserID:     userID,
+			PageSize:   int32(syncPageSize + 1),
+		})
+		if err != nil {
+			logAndSendError(w, err, "Error getting changes", http.StatusInternalServerError)
+			return
+		}
+
+		if len(changes) > syncPageSize {
+			res.HasMore = true
+			changes = changes[:syncPageSize]
+			last := changes[len(changes)-1]
+			res.Cursor = syncCursor{txID: last.TxID, id: last.ID}.String()
+		}
+
+		var joined []int32
+		keys, joined, res.Deleted = collectChanges(changes)
+
+		// joining a class makes every set in it visible
+		if len(joined) > 0 {
+			classSets, err := qtx.ListSyncClassSet(ctx, joined)
+			if err != nil {
+				logAndSendError(w, err, "Error getting class sets", http.StatusInternalServerError)
+				return
+			}
+			for _, cs := range classSets {
+				keys.fullSets = append(keys.fullSets, cs.SetID)
+			}
+		}
+
+		keys, err = filterVisibleSets(ctx, qtx, userID, keys, &res)
+		if err != nil {
+			logAndSendError(w, err, "Error getting sets", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = loadSyncRows(ctx, qtx, userID, keys, &res)
+	if err != nil {
+		logAndSendError(w, err, "Error getting changed rows", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+func (h *DBHandler) PushChanges(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/sync -d '{"flashcards": [{"op": "update", "id": 1, "front": "Hello", "back": "Hola", "base_updated_at": "2025-04-01T08:30:00.12Z"}]}'
+
+	var req SyncPushRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSyncPushBytes)).Decode(&req); err != nil {
+		logAndSendError(w, err, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Sets)+len(req.Flashcards) > syncPageSize {
+		logAndSendError(w, errors.New("too many edits"), "Push at most 1000 edits at a time", http.StatusBadRequest)
+		return
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	p := syncPusher{tx: tx, query: query.WithTx(tx), userID: userID, owned: map[int32]bool{}}
+
+	// set before any savepoint, rolling one back would undo it
+	err = p.query.SetRevisionAuthor(ctx, userID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to set revision author", http.StatusInternalServerError)
+		return
+	}
+
+	res := SyncPushResponse{
+		Sets:       make([]SyncEditResult, len(req.Sets)),
+		Flashcards: make([]SyncEditResult, len(req.Flashcards)),
+	}
+
+	for i, edit := range req.Sets {
+		res.Sets[i] = p.run(ctx, SyncEditResult{ID: edit.ID}, func(q *db.Queries, result *SyncEditResult) error {
+			return p.editSet(ctx, q, edit, result)
+		})
+	}
+	for i, edit := range req.Flashcards {
+		res.Flashcards[i] = p.run(ctx, SyncEditResult{ClientID: edit.ClientID, ID: edit.ID}, func(q *db.Queries, result *SyncEditResult) error {
+			return p.editFlashcard(ctx, q, edit, result)
+		})
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// a position in the change log, the zero value is before everything
+type syncCursor struct {
+	txID, id int64
+}
+
+func (c syncCursor) String() string {
+	if c == (syncCursor{}) {
+		return "0"
+	}
+	return fmt.Sprintf("%d:%d", c.txID, c.id)
+}
+
+func parseSyncCursor(val string) (c syncCursor, err error) {
+	if val == "0" {
+		return c, nil
+	}
+
+	txID, id, ok := strings.Cut(val, ":")
+	if !ok {
+		return c, errors.New("cursor must be 0 or tx_id:id")
+	}
+	if c.txID, err = strconv.ParseInt(txID, 10, 64); err != nil || c.txID < 0 {
+		return c, errors.New("invalid cursor tx_id")
+	}
+	if c.id, err = strconv.ParseInt(id, 10, 64); err != nil || c.id < 0 {
+		return c, errors.New("invalid cursor id")
+	}
+	return c, nil
+}
+
+// PruneChangeLog drops change log entries and pushed client_ids older than syncRetentionDays,
+// now and then once a day until ctx is done
+func (h *DBHandler) PruneChangeLog(ctx context.Context) {
+	query := db.New(h.DB)
+	for {
+		if err := query.PruneChangeLog(ctx, int32(syncRetentionDays)); err != nil {
+			log.Printf("pruning change_log: %v", err)
+		}
+		if err := query.PruneSyncCreates(ctx, int32(syncRetentionDays)); err != nil {
+			log.Printf("pruning sync_creates: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(24 * time.Hour):
+		}
+	}
+}
+
+// collectChanges keeps the last change per row. Deletes become tombstones, upserts become ids
+// to load. joined holds classes the user joined, whose sets all need loading
+func collectChanges(changes []db.ListChangesForUserRow) (keys syncKeys, joined []int32, deleted []SyncTombstone) {
+	type rowKey struct {
+		entity                 string
+		setID, cardID, classID int32
+	}
+
+	var order []rowKey
+	last := map[rowKey]db.ListChangesForUserRow{}
+	for _, c := range changes {
+		k := rowKey{c.Entity, c.SetID.Int32, c.CardID.Int32, c.ClassID.Int32}
+		if _, seen := last[k]; !seen {
+			order = append(order, k)
+		}
+		last[k] = c
+	}
+
+	for _, k := range order {
+		if last[k].Op == syncDelete {
+			deleted = append(deleted, SyncTombstone{Entity: k.entity, SetID: k.setID, CardID: k.cardID, ClassID: k.classID})
+			continue
+		}
+
+		switch k.entity {
+		case "flashcard_sets":
+			keys.sets = append(keys.sets, k.setID)
+		case "flashcards":
+			keys.cards = append(keys.cards, k.cardID)
+		case "set_user":
+			keys.fullSets = append(keys.fullSets, k.setID)
+		case "class_set":
+			keys.fullSets = append(keys.fullSets, k.setID)
+			keys.classes = append(keys.classes, k.classID)
+		case "class_user":
+			keys.classes = append(keys.classes, k.classID)
+			joined = append(joined, k.classID)
+		case "card_history":
+			keys.history = append(keys.history, k.cardID)
+		}
+	}
+
+	return
+}
+
+// filterVisibleSets drops the sets the user can't sync from keys. A changed set they can no
+// longer see went private and is tombstoned; one they can see is loaded whole, as it may be
+// coming back from private
+func filterVisibleSets(ctx context.Context, query *db.Queries, userID int32, keys syncKeys, res *SyncResponse) (syncKeys, error) {
+	ids := slices.Concat(keys.sets, keys.fullSets)
+	if len(ids) == 0 {
+		return keys, nil
+	}
+
+	visibleIDs, err := query.FilterVisibleSetIds(ctx, db.FilterVisibleSetIdsParams{
+		SetIds: ids,
+		UserID: userID,
+	})
+	if err != nil {
+		return keys, err
+	}
+
+	visible := make(map[int32]bool, len(visibleIDs))
+	for _, id := range visibleIDs {
+		visible[id] = true
+	}
+
+	var fullSets []int32
+	for _, id := range keys.sets {
+		if visible[id] {
+			fullSets = append(fullSets, id)
+		} else {
+			res.Deleted = append(res.Deleted, SyncTombstone{Entity: "flashcard_sets", SetID: id})
+		}
+	}
+	for _, id := range keys.fullSets {
+		if visible[id] {
+			fullSets = append(fullSets, id)
+		}
+	}
+
+	keys.sets, keys.fullSets = nil, fullSets
+	return keys, nil
+}
+
+func loadSyncRows(ctx context.Context, query *db.Queries, userID int32, keys syncKeys, res *SyncResponse) (err error) {
+	res.Sets, err = query.ListSyncSets(ctx, append(keys.sets, keys.fullSets...))
+	if err != nil {
+		return err
+	}
+
+	res.Flashcards, err = query.ListSyncFlashcards(ctx, db.ListSyncFlashcardsParams{
+		CardIds: keys.cards,
+		SetIds:  keys.fullSets,
+	})
+	if err != nil {
+		return err
+	}
+
+	res.SetUser, err = query.ListSyncSetUser(ctx, db.ListSyncSetUserParams{
+		UserID: userID,
+		SetIds: keys.fullSets,
+	})
+	if err != nil {
+		return err
+	}
+
+	res.ClassSet, err = query.ListSyncClassSet(ctx, keys.classes)
+	if err != nil {
+		return err
+	}
+
+	res.ClassUser, err = query.ListSyncClassUser(ctx, db.ListSyncClassUserParams{
+		UserID:   userID,
+		ClassIds: keys.classes,
+	})
+	if err != nil {
+		return err
+	}
+
+	res.CardHistory, err = query.ListSyncCardHistory(ctx, db.ListSyncCardHistoryParams{
+		UserID:  userID,
+		CardIds: keys.history,
+		SetIds:  keys.fullSets,
+	})
+
+	return err
+}
+
+// syncPusher applies pushed edits, each in its own savepoint
+type syncPusher struct {
+	tx     pgx.Tx
+	query  *db.Queries
+	userID int32
+	owned  map[int32]bool // set id -> caller is owner
+}
+
+func (p *syncPusher) run(ctx context.Context, result SyncEditResult, apply func(*db.Queries, *SyncEditResult) error) SyncEditResult {
+	sp, err := p.tx.Begin(ctx)
+	if err != nil {
+		log.Printf("sync push savepoint: %v", err)
+		result.Status = statusError
+		return result
+	}
+	defer sp.Rollback(ctx)
+
+	err = apply(p.query.WithTx(sp), &result)
+	if err == nil {
+		err = sp.Commit(ctx)
+	}
+	if err != nil {
+		log.Printf("sync push %d: %v", result.ID, err)
+		result.Status, result.Current = statusError, nil
+	}
+
+	return result
+}
+
+func (p *syncPusher) isOwner(ctx context.Context, query *db.Queries, setID int32) (bool, error) {
+	if owned, ok := p.owned[setID]; ok {
+		return owned, nil
+	}
+
+	member, err := query.VerifySetMember(ctx, db.VerifySetMemberParams{
+		SetID:  setID,
+		UserID: p.userID,
+	})
+	if err != nil && !strings.Contains(err.Error(), "no rows") {
+		return false, err
+	}
+
+	p.owned[setID] = err == nil && member.Role == owner
+	return p.owned[setID], nil
+}
+
+func (p *syncPusher) editSet(ctx context.Context, query *db.Queries, edit SyncSetEdit, result *SyncEditResult) error {
+	edit.SetName = strings.TrimSpace(edit.SetName)
+	switch {
+	case edit.SetName == "":
+		result.Status, result.Error = statusError, "set name is required"
+		return nil
+	case utf8.RuneCountInString(edit.SetName) > maxCardSide || utf8.RuneCountInString(edit.SetDescription) > maxCardSide:
+		result.Status, result.Error = statusError, fmt.Sprintf("set names and descriptions are limited to %d characters", maxCardSide)
+		return nil
+	}
+
+	owned, err := p.isOwner(ctx, query, edit.ID)
+	if err != nil {
+		return err
+	}
+	if !owned {
+		result.Status = statusForbidden
+		return nil
+	}
+
+	set, err := query.SyncUpdateFlashcardSet(ctx, db.SyncUpdateFlashcardSetParams{
+		SetName:        edit.SetName,
+		SetDescription: edit.SetDescription,
+		ID:             edit.ID,
+		BaseUpdatedAt:  pgtype.Timestamp{Time: edit.BaseUpdatedAt, Valid: true},
+	})
+	if err == nil {
+		result.Status, result.Current = statusApplied, set
+		return nil
+	} else if !strings.Contains(err.Error(), "no rows") {
+		return err
+	}
+
+	set, err = query.GetFlashcardSetById(ctx, edit.ID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			result.Status = statusGone
+			return nil
+		}
+		return err
+	}
+
+	result.Status, result.Current = statusConflict, set
+	return nil
+}
+
+func (p *syncPusher) editFlashcard(ctx context.Context, query *db.Queries, edit SyncFlashcardEdit, result *SyncEditResult) error {
+	if msg := checkFlashcardEdit(&edit); msg != "" {
+		result.Status, result.Error = statusError, msg
+		return nil
+	}
+
+	setID := edit.SetID
+	if edit.Op != syncCreate {
+		card, err := query.GetFlashcardById(ctx, edit.ID)
+		if err != nil {
+			if strings.Contains(err.Error(), "no rows") {
+				result.Status = statusGone
+				return nil
+			}
+			return err
+		}
+		setID = card.SetID
+	}
+
+	owned, err := p.isOwner(ctx, query, setID)
+	if err != nil {
+		return err
+	}
+	if !owned {
+		result.Status = statusForbidden
+		return nil
+	}
+
+	base := pgtype.Timestamp{Time: edit.BaseUpdatedAt, Valid: true}
+
+	switch edit.Op {
+	case syncCreate:
+		return p.createFlashcard(ctx, query, edit, setID, result)
+	case syncUpdate:
+		card, err := query.SyncUpdateFlashcard(ctx, db.SyncUpdateFlashcardParams{
+			Front:         edit.Front,
+			Back:          edit.Back,
+			ID:            edit.ID,
+			BaseUpdatedAt: base,
+		})
+		if err == nil {
+			result.Status, result.Current = statusApplied, card
+			return nil
+		} else if !strings.Contains(err.Error(), "no rows") {
+			return err
+		}
+	case syncDelete:
+		rows, err := query.SyncDeleteFlashcard(ctx, db.SyncDeleteFlashcardParams{
+			ID:            edit.ID,
+			BaseUpdatedAt: base,
+		})
+		if err != nil {
+			return err
+		}
+		if rows > 0 {
+			result.Status = statusApplied
+			return nil
+		}
+	default:
+		result.Status = statusError
+		return nil
+	}
+
+	// the card exists but has moved past the device's copy
+	card, err := query.GetFlashcardById(ctx, edit.ID)
+	if err != nil {
+		return err
+	}
+
+	result.Status, result.Current = statusConflict, card
+	return nil
+}
+
+// createFlashcard makes the card unless an earlier push with the same client_id already did,
+// in which case that card is sent back as applied
+func (p *syncPusher) createFlashcard(ctx context.Context, query *db.Queries, edit SyncFlashcardEdit, setID int32, result *SyncEditResult) error {
+	claimed, err := query.ClaimSyncCreate(ctx, db.ClaimSyncCreateParams{
+		UserID:   p.userID,
+		ClientID: edit.ClientID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if claimed == 0 {
+		created, err := query.GetSyncCreate(ctx, db.GetSyncCreateParams{
+			UserID:   p.userID,
+			ClientID: edit.ClientID,
+		})
+		if err != nil {
+			return err
+		}
+		if !created.CardID.Valid {
+			result.Status = statusGone
+			return nil
+		}
+
+		card, err := query.GetFlashcardById(ctx, created.CardID.Int32)
+		if err != nil {
+			return err
+		}
+		result.ID, result.Status, result.Current = card.ID, statusApplied, card
+		return nil
+	}
+
+	card, err := query.CreateFlashcard(ctx, db.CreateFlashcardParams{
+		Front: edit.Front,
+		Back:  edit.Back,
+		SetID: setID,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = query.SetSyncCreateCard(ctx, db.SetSyncCreateCardParams{
+		CardID:   pgtype.Int4{Int32: card.ID, Valid: true},
+		UserID:   p.userID,
+		ClientID: edit.ClientID,
+	})
+	if err != nil {
+		return err
+	}
+
+	result.ID, result.Status, result.Current = card.ID, statusApplied, card
+	return nil
+}
+
+// checkFlashcardEdit trims the sides of a create or update and checks them the way an import
+// row is checked. It returns what's wrong with the edit, if anything
+func checkFlashcardEdit(edit *SyncFlashcardEdit) string {
+	if edit.Op == syncCreate && (edit.ClientID == "" || len(edit.ClientID) > maxClientID) {
+		return fmt.Sprintf("creates need a client_id of at most %d characters", maxClientID)
+	}
+	if edit.Op != syncCreate && edit.Op != syncUpdate {
+		return ""
+	}
+
+	row := newImportRow(0, []string{edit.Front, edit.Back})
+	edit.Front, edit.Back = row.front, row.back
+	return row.err
+}
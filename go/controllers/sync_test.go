@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckFlashcardEdit(t *testing.T) {
+	long := strings.Repeat("a", maxCardSide+1)
+
+	tests := []struct {
+		name      string
+		edit      SyncFlashcardEdit
+		want      string
+		wantFront string
+	}{
+		{"create", SyncFlashcardEdit{Op: syncCreate, ClientID: "c1", Front: " hola ", Back: "hello"}, "", "hola"},
+		{"create without client_id", SyncFlashcardEdit{Op: syncCreate, Front: "hola", Back: "hello"}, "client_id", "hola"},
+		{"long client_id", SyncFlashcardEdit{Op: syncCreate, ClientID: long, Front: "hola", Back: "hello"}, "client_id", "hola"},
+		{"empty front", SyncFlashcardEdit{Op: syncUpdate, Front: " ", Back: "hello"}, "term is empty", ""},
+		{"empty back", SyncFlashcardEdit{Op: syncUpdate, Front: "hola"}, "definition is empty", "hola"},
+		{"too long", SyncFlashcardEdit{Op: syncUpdate, Front: "hola", Back: long}, "limited to", "hola"},
+		{"delete has no sides", SyncFlashcardEdit{Op: syncDelete}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkFlashcardEdit(&tt.edit)
+			if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if tt.want == "" && tt.edit.Front != tt.wantFront {
+				t.Errorf("got front %q, want %q", tt.edit.Front, tt.wantFront)
+			}
+		})
+	}
+}
@@ -0,0 +1,572 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/quiz"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// timed tests are assessments, so unlike quizzes their answers stay out of
+// card_history and set_score
+
+const (
+	defaultTestQuestions int32 = 20
+	maxTestSeconds       int32 = 4 * 60 * 60
+	maxTestAttempts      int32 = 10
+)
+
+func (h *DBHandler) CreateTest(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/tests/ -H "id: 1" -d '{"test_name": "Unit 3", "set_ids": [1, 2], "question_count": 20, "time_limit_seconds": 900, "max_attempts": 2}'
+
+	var req TestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logAndSendError(w, err, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.QuestionCount == 0 {
+		req.QuestionCount = defaultTestQuestions
+	}
+	if strings.TrimSpace(req.TestName) == "" || len(req.SetIDs) == 0 || req.QuestionCount < 1 || req.QuestionCount > int32(maxQuizQuestions) {
+		logAndSendError(w, errors.New("invalid test"), fmt.Sprintf("A test needs a name, at least one set and 1 to %d questions", maxQuizQuestions), http.StatusBadRequest)
+		return
+	}
+	if req.TimeLimitSeconds < 1 || req.TimeLimitSeconds > maxTestSeconds {
+		logAndSendError(w, errors.New("invalid time limit"), fmt.Sprintf("Time limit must be between 1 and %d seconds", maxTestSeconds), http.StatusBadRequest)
+		return
+	}
+	if req.MaxAttempts == 0 {
+		req.MaxAttempts = 1
+	}
+	if req.MaxAttempts < 1 || req.MaxAttempts > maxTestAttempts {
+		logAndSendError(w, errors.New("invalid max attempts"), fmt.Sprintf("Max attempts must be between 1 and %d", maxTestAttempts), http.StatusBadRequest)
+		return
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || role != teacher {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	test, err := qtx.CreateTest(ctx, db.CreateTestParams{
+		ClassID:          classID,
+		TestName:         req.TestName,
+		QuestionCount:    req.QuestionCount,
+		TimeLimitSeconds: req.TimeLimitSeconds,
+		MaxAttempts:      req.MaxAttempts,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to create test", http.StatusInternalServerError)
+		return
+	}
+
+	for _, setID := range req.SetIDs {
+		err = qtx.AddSetToTest(ctx, db.AddSetToTestParams{
+			TestID: test.ID,
+			SetID:  setID,
+		})
+		if err != nil {
+			logAndSendError(w, err, "Failed to add set to test", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// duplicates would already have failed on the test_sets primary key
+	inClass, err := qtx.CountSetsInAClass(ctx, db.CountSetsInAClassParams{
+		ClassID: classID,
+		SetIds:  req.SetIDs,
+	})
+	if err != nil {
+		logAndSendError(w, err, "Failed to verify sets", http.StatusInternalServerError)
+		return
+	}
+	if int(inClass) != len(req.SetIDs) {
+		logAndSendError(w, errors.New("set not in class"), "Every set must belong to the class", http.StatusBadRequest)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(test); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+func (h *DBHandler) ListTestsInAClass(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/tests/list -H "id: 1"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	role, ok := middleware.GetRoleFromContext(ctx)
+	if !ok || (role != teacher && role != student) {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, ok := middleware.GetClassIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tests, err := query.ListTestsInAClass(ctx, classID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting tests", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(tests); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// teachers only; an attempt without a score was never submitted
+func (h *DBHandler) ListTestAttempts(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/tests/attempts -H "test_id: 1"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, test_id)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	testID, err := getInt32Id(headerVals[test_id])
+	if err != nil {
+		logAndSendError(w, err, "Invalid test id", http.StatusBadRequest)
+		return
+	}
+
+	test, err := query.GetTestOfAMember(ctx, db.GetTestOfAMemberParams{
+		ID:     testID,
+		UserID: userID,
+	})
+	if err != nil || test.Role != teacher {
+		logAndSendError(w, errors.New("forbidden"), "not a teacher", http.StatusUnauthorized)
+		return
+	}
+
+	attempts, err := query.ListTestAttempts(ctx, testID)
+	if err != nil {
+		logAndSendError(w, err, "Error getting attempts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(attempts); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// starting again while an attempt is still running returns that attempt, so a
+// refresh doesn't hand out a fresh set of questions or reset the clock. Once the
+// test's max_attempts have been used, submitted or expired, no new one is started
+func (h *DBHandler) StartTestAttempt(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/tests/start -H "test_id: 1"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, test_id)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	testID, err := getInt32Id(headerVals[test_id])
+	if err != nil {
+		logAndSendError(w, err, "Invalid test id", http.StatusBadRequest)
+		return
+	}
+
+	test, err := query.GetTestOfAMember(ctx, db.GetTestOfAMemberParams{
+		ID:     testID,
+		UserID: userID,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			logAndSendError(w, err, "Not a member of the test's class", http.StatusUnauthorized)
+			return
+		}
+		logAndSendError(w, err, "Error getting test", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	attempt, err := qtx.GetOpenTestAttempt(ctx, db.GetOpenTestAttemptParams{
+		TestID: testID,
+		UserID: userID,
+	})
+	if err != nil && !strings.Contains(err.Error(), "no rows") {
+		logAndSendError(w, err, "Error getting attempt", http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if err != nil {
+		status = http.StatusCreated
+
+		attempt, err = newTestAttempt(ctx, qtx, userID, test)
+		if errors.Is(err, quiz.ErrTooFewCards) {
+			logAndSendError(w, err, "Not enough flashcards for a test", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, errNoAttemptsLeft) {
+			logAndSendError(w, err, "No attempts left for this test", http.StatusConflict)
+			return
+		}
+		if err != nil && strings.Contains(err.Error(), "duplicate key") {
+			logAndSendError(w, err, "Test was started at the same time, try again", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			logAndSendError(w, err, "Failed to start test", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	res, err := getTestAttemptResponse(ctx, qtx, attempt, false)
+	if err != nil {
+		logAndSendError(w, err, "Error getting attempt questions", http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// answers are only accepted until the deadline (plus a short grace period); a late
+// submit still closes the attempt, scored on nothing
+func (h *DBHandler) SubmitTestAttempt(w http.ResponseWriter, r *http.Request) {
+	// curl -X POST localhost:8000/api/tests/submit -d '{"attempt_id": 1, "answers": [{"position": 0, "choice": 2}]}'
+
+	var req TestSubmitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logAndSendError(w, err, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Answers) > maxQuizQuestions {
+		logAndSendError(w, errors.New("answer count"), fmt.Sprintf("Send at most %d answers", maxQuizQuestions), http.StatusBadRequest)
+		return
+	}
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Database tx connection error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := query.WithTx(tx)
+
+	attempt, err := qtx.GetTestAttempt(ctx, req.AttemptID)
+	if err != nil || attempt.UserID != userID {
+		logAndSendError(w, errors.New("attempt not found"), "Attempt not found", http.StatusNotFound)
+		return
+	}
+	if attempt.SubmittedAt.Valid {
+		logAndSendError(w, errors.New("already submitted"), "Attempt already submitted", http.StatusConflict)
+		return
+	}
+
+	if !attempt.Expired {
+		for _, answer := range req.Answers {
+			if answer.Choice < 0 || answer.Choice >= int32(quiz.MaxChoices) {
+				continue
+			}
+
+			err = qtx.AnswerTestAttemptQuestion(ctx, db.AnswerTestAttemptQuestionParams{
+				ChosenIndex: pgtype.Int4{Int32: answer.Choice, Valid: true},
+				AttemptID:   attempt.ID,
+				Position:    answer.Position,
+			})
+			if err != nil {
+				logAndSendError(w, err, "Failed to record answer", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	submitted, err := qtx.SubmitTestAttempt(ctx, attempt.ID)
+	if err != nil {
+		logAndSendError(w, err, "Failed to submit attempt", http.StatusInternalServerError)
+		return
+	}
+
+	res, err := getTestAttemptResponse(ctx, qtx, submitted, true)
+	if err != nil {
+		logAndSendError(w, err, "Error getting attempt questions", http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logAndSendError(w, err, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// students see their own attempts, teachers any attempt in their class
+func (h *DBHandler) GetTestAttempt(w http.ResponseWriter, r *http.Request) {
+	// curl -X GET localhost:8000/api/tests/attempt -H "attempt_id: 1"
+
+	query, ctx, conn, err := getQueryConnAndContext(r, h)
+	if err != nil {
+		logAndSendError(w, err, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Release()
+
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		logAndSendError(w, errContext, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	headerVals, err := getHeaderVals(r, attempt_id)
+	if err != nil {
+		logAndSendError(w, err, "Header error", http.StatusBadRequest)
+		return
+	}
+
+	attemptID, err := getInt32Id(headerVals[attempt_id])
+	if err != nil {
+		logAndSendError(w, err, "Invalid attempt id", http.StatusBadRequest)
+		return
+	}
+
+	attempt, err := query.GetTestAttempt(ctx, attemptID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			logAndSendError(w, err, "Attempt not found", http.StatusNotFound)
+			return
+		}
+		logAndSendError(w, err, "Error getting attempt", http.StatusInternalServerError)
+		return
+	}
+
+	if attempt.UserID != userID {
+		member, err := query.VerifyClassMember(ctx, db.VerifyClassMemberParams{
+			ClassID: attempt.ClassID,
+			UserID:  userID,
+		})
+		if err != nil || member.Role != teacher {
+			logAndSendError(w, errors.New("forbidden"), "not a teacher", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	res, err := getTestAttemptResponse(ctx, query, db.TestAttempt{
+		ID:          attempt.ID,
+		TestID:      attempt.TestID,
+		UserID:      attempt.UserID,
+		Attempt:     attempt.Attempt,
+		Seed:        attempt.Seed,
+		Total:       attempt.Total,
+		Score:       attempt.Score,
+		StartedAt:   attempt.StartedAt,
+		Deadline:    attempt.Deadline,
+		SubmittedAt: attempt.SubmittedAt,
+	}, attempt.SubmittedAt.Valid || attempt.Expired)
+	if err != nil {
+		logAndSendError(w, err, "Error getting attempt questions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		logAndSendError(w, err, "Error encoding message", http.StatusInternalServerError)
+	}
+}
+
+// newTestAttempt draws the questions for a new attempt and copies them into it
+func newTestAttempt(ctx context.Context, query *db.Queries, userID int32, test db.GetTestOfAMemberRow) (db.TestAttempt, error) {
+	rows, err := query.ListTestCards(ctx, test.ID)
+	if err != nil {
+		return db.TestAttempt{}, err
+	}
+
+	cards := make([]quiz.Card, len(rows))
+	for i, row := range rows {
+		cards[i] = quiz.Card{ID: row.ID, Front: row.Front, Back: row.Back}
+	}
+
+	attemptSeed := rand.Int64()
+	questions, err := quiz.Generate(cards, int(test.QuestionCount), attemptSeed)
+	if err != nil {
+		return db.TestAttempt{}, err
+	}
+
+	attempt, err := query.StartTestAttempt(ctx, db.StartTestAttemptParams{
+		TestID:           test.ID,
+		UserID:           userID,
+		Seed:             attemptSeed,
+		Total:            int32(len(questions)),
+		TimeLimitSeconds: test.TimeLimitSeconds,
+		MaxAttempts:      test.MaxAttempts,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			return db.TestAttempt{}, errNoAttemptsLeft
+		}
+		return db.TestAttempt{}, err
+	}
+
+	for i, q := range questions {
+		err = query.AddTestAttemptQuestion(ctx, db.AddTestAttemptQuestionParams{
+			AttemptID:   attempt.ID,
+			Position:    int32(i),
+			CardID:      pgtype.Int4{Int32: q.CardID, Valid: true},
+			Prompt:      q.Prompt,
+			Choices:     q.Choices,
+			AnswerIndex: q.Answer,
+		})
+		if err != nil {
+			return db.TestAttempt{}, err
+		}
+	}
+
+	return attempt, nil
+}
+
+func getTestAttemptResponse(ctx context.Context, query *db.Queries, attempt db.TestAttempt, reveal bool) (TestAttemptResponse, error) {
+	questions, err := query.ListTestAttemptQuestions(ctx, attempt.ID)
+	if err != nil {
+		return TestAttemptResponse{}, err
+	}
+
+	res := TestAttemptResponse{
+		ID:          attempt.ID,
+		TestID:      attempt.TestID,
+		UserID:      attempt.UserID,
+		Attempt:     attempt.Attempt,
+		Score:       attempt.Score,
+		Total:       attempt.Total,
+		StartedAt:   attempt.StartedAt,
+		Deadline:    attempt.Deadline,
+		SubmittedAt: attempt.SubmittedAt,
+		Questions:   make([]QuizQuestion, len(questions)),
+	}
+	for i, q := range questions {
+		res.Questions[i] = QuizQuestion{
+			Position: q.Position,
+			CardID:   q.CardID.Int32,
+			Prompt:   q.Prompt,
+			Choices:  q.Choices,
+		}
+		if reveal {
+			if q.ChosenIndex.Valid {
+				res.Questions[i].Chosen = &q.ChosenIndex.Int32
+			}
+			res.Questions[i].Answer = &q.AnswerIndex
+		}
+	}
+
+	return res, nil
+}
@@ -109,6 +109,9 @@ func (h *DBHandler) GetUserById(w http.ResponseWriter, r *http.Request) {
 		CardsStudied:   int(cardsStudied),
 		CardsMastered:  int(cardsMastered),
 		TotalCardViews: totalCardViews,
+
+		LeaderboardOptIn:      user.LeaderboardOptIn,
+		LeaderboardVisibility: user.LeaderboardVisibility,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
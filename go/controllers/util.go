@@ -2,11 +2,18 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/db"
+	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/grading"
 	"github.com/HSU-Senior-Project-2025/Cowboy_Cards/go/middleware"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -29,6 +36,9 @@ type User struct {
 	CardsStudied   int `json:"cardsStudied"`
 	CardsMastered  int `json:"cardsMastered"`
 	TotalCardViews any `json:"totalCardViews"`
+
+	LeaderboardOptIn      bool   `json:"leaderboard_opt_in"`
+	LeaderboardVisibility string `json:"leaderboard_visibility"`
 }
 
 type Class struct {
@@ -47,6 +57,8 @@ type FlashcardSet struct {
 	CreatedAt      string
 	UpdatedAt      string
 	Role           string
+	Visibility     string
+	ShareToken     string `json:",omitempty"` // owners only
 }
 
 // LoginRequest represents the login request body
@@ -64,32 +76,504 @@ type SignupRequest struct {
 	LastName  string `json:"last_name"`
 }
 
+// ReviewRequest represents one graded answer to a card
+type ReviewRequest struct {
+	CardID     int32  `json:"card_id"`
+	Grade      string `json:"grade"`
+	ResponseMs *int32 `json:"response_ms"`
+	Answer     string `json:"answer"`
+	// set by the mobile app when reviews are queued offline
+	ClientID   string     `json:"client_id"`
+	ReviewedAt *time.Time `json:"reviewed_at"`
+}
+
+// TypedAnswerRequest represents an answer typed out in typed-answer mode
+type TypedAnswerRequest struct {
+	CardID     int32  `json:"card_id"`
+	Answer     string `json:"answer"`
+	ResponseMs *int32 `json:"response_ms"`
+}
+
+// TypedAnswerResult is the server's grading of a typed answer, with a diff against the back
+type TypedAnswerResult struct {
+	grading.Result
+	Grade    string `json:"grade"`
+	Expected string `json:"expected"`
+}
+
+// BatchReviewRequest represents reviews queued on a device while offline
+type BatchReviewRequest struct {
+	Reviews []ReviewRequest `json:"reviews"`
+}
+
+// ReviewResult reports what happened to one review of a batch
+type ReviewResult struct {
+	ClientID string `json:"client_id"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// SyncResponse carries everything that changed for a user since their cursor
+type SyncResponse struct {
+	Cursor      string               `json:"cursor"`
+	HasMore     bool                 `json:"has_more"`
+	Sets        []db.ListSyncSetsRow `json:"sets"`
+	Flashcards  []db.Flashcard       `json:"flashcards"`
+	SetUser     []db.SetUser         `json:"set_user"`
+	ClassSet    []db.ClassSet        `json:"class_set"`
+	ClassUser   []db.ClassUser       `json:"class_user"`
+	CardHistory []db.CardHistory     `json:"card_history"`
+	Deleted     []SyncTombstone      `json:"deleted"`
+}
+
+// SyncTombstone identifies a row that was deleted; which ids are set depends on the entity
+type SyncTombstone struct {
+	Entity  string `json:"entity"`
+	SetID   int32  `json:"set_id,omitempty"`
+	CardID  int32  `json:"card_id,omitempty"`
+	ClassID int32  `json:"class_id,omitempty"`
+}
+
+// SyncPushRequest represents edits made on a device while offline
+type SyncPushRequest struct {
+	Sets       []SyncSetEdit       `json:"sets"`
+	Flashcards []SyncFlashcardEdit `json:"flashcards"`
+}
+
+// base_updated_at is the updated_at the device last pulled for the row
+type SyncSetEdit struct {
+	ID             int32     `json:"id"`
+	SetName        string    `json:"set_name"`
+	SetDescription string    `json:"set_description"`
+	BaseUpdatedAt  time.Time `json:"base_updated_at"`
+}
+
+type SyncFlashcardEdit struct {
+	ClientID      string    `json:"client_id"`
+	Op            string    `json:"op"`
+	ID            int32     `json:"id"`
+	SetID         int32     `json:"set_id"`
+	Front         string    `json:"front"`
+	Back          string    `json:"back"`
+	BaseUpdatedAt time.Time `json:"base_updated_at"`
+}
+
+// SyncEditResult reports one pushed edit; Current is the server copy after the edit,
+// or the copy that won on a conflict
+type SyncEditResult struct {
+	ClientID string `json:"client_id,omitempty"`
+	ID       int32  `json:"id"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Current  any    `json:"current,omitempty"`
+}
+
+type SyncPushResponse struct {
+	Sets       []SyncEditResult `json:"sets"`
+	Flashcards []SyncEditResult `json:"flashcards"`
+}
+
+// QuizResponse is a generated quiz; Answer is only filled in once the question was answered
+type QuizResponse struct {
+	ID        int32          `json:"id"`
+	SetID     int32          `json:"set_id"`
+	Questions []QuizQuestion `json:"questions"`
+}
+
+type QuizQuestion struct {
+	Position int32    `json:"position"`
+	CardID   int32    `json:"card_id"`
+	Prompt   string   `json:"prompt"`
+	Choices  []string `json:"choices"`
+	Chosen   *int32   `json:"chosen,omitempty"`
+	Answer   *int32   `json:"answer,omitempty"`
+}
+
+// QuizAnswerRequest represents answers to some or all questions of a quiz
+type QuizAnswerRequest struct {
+	QuizID  int32        `json:"quiz_id"`
+	Answers []QuizAnswer `json:"answers"`
+}
+
+type QuizAnswer struct {
+	Position   int32  `json:"position"`
+	Choice     int32  `json:"choice"`
+	ResponseMs *int32 `json:"response_ms"`
+}
+
+// QuizAnswerResult reports one graded answer; an already answered question is a duplicate
+type QuizAnswerResult struct {
+	Position int32  `json:"position"`
+	Status   string `json:"status"`
+	Correct  bool   `json:"correct"`
+	Answer   int32  `json:"answer"`
+	Error    string `json:"error,omitempty"`
+}
+
+// TestRequest represents a timed test drawn from sets of a class
+type TestRequest struct {
+	TestName         string  `json:"test_name"`
+	SetIDs           []int32 `json:"set_ids"`
+	QuestionCount    int32   `json:"question_count"`
+	TimeLimitSeconds int32   `json:"time_limit_seconds"`
+	MaxAttempts      int32   `json:"max_attempts"` // defaults to one
+}
+
+// TestAttemptResponse is one student's sitting of a test; answers are only
+// included once the attempt is over
+type TestAttemptResponse struct {
+	ID          int32            `json:"id"`
+	TestID      int32            `json:"test_id"`
+	UserID      int32            `json:"user_id"`
+	Attempt     int32            `json:"attempt"`
+	Score       pgtype.Int4      `json:"score"`
+	Total       int32            `json:"total"`
+	StartedAt   pgtype.Timestamp `json:"started_at"`
+	Deadline    pgtype.Timestamp `json:"deadline"`
+	SubmittedAt pgtype.Timestamp `json:"submitted_at"`
+	Questions   []QuizQuestion   `json:"questions"`
+}
+
+// TestSubmitRequest represents the answers to a test attempt
+type TestSubmitRequest struct {
+	AttemptID int32        `json:"attempt_id"`
+	Answers   []QuizAnswer `json:"answers"`
+}
+
+// AssignmentRequest represents "study this set by then"; at least one of
+// required_mastery_pct and min_reviews must be set
+type AssignmentRequest struct {
+	ID                 int32     `json:"id"`
+	SetID              int32     `json:"set_id"`
+	DueAt              time.Time `json:"due_at"`
+	RequiredMasteryPct *int32    `json:"required_mastery_pct"`
+	MinReviews         *int32    `json:"min_reviews"`
+	Instructions       string    `json:"instructions"`
+}
+
+// AssignmentStatus is a student's progress on one assignment
+type AssignmentStatus struct {
+	db.ListAssignmentsOfAUserRow
+	MasteryPct int32 `json:"mastery_pct"`
+	Complete   bool  `json:"complete"`
+}
+
+// SetVisibilityResponse is a set's new visibility; the token is only set while it's unlisted
+type SetVisibilityResponse struct {
+	Visibility string `json:"visibility"`
+	ShareToken string `json:"share_token,omitempty"`
+}
+
+// JoinCodeRequest represents a teacher's join code settings; a nil expires_at never expires
+type JoinCodeRequest struct {
+	Enabled   *bool      `json:"enabled"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// RosterImportResponse reports what a csv roster import did, or would do on a dry run
+type RosterImportResponse struct {
+	DryRun bool                 `json:"dry_run"`
+	Rows   []RosterImportResult `json:"rows"`
+}
+
+// RosterImportResult is the outcome of one csv line: created, enrolled, skipped or error
+type RosterImportResult struct {
+	Row      int    `json:"row"`
+	Email    string `json:"email"`
+	Username string `json:"username,omitempty"`
+	Status   string `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// CardImportResponse reports what a flashcard import did, or would do on a dry run
+type CardImportResponse struct {
+	DryRun   bool               `json:"dry_run"`
+	Imported int                `json:"imported"`
+	Rows     []CardImportResult `json:"rows"`
+}
+
+// CardImportResult is the outcome of one imported row: created, duplicate or error
+type CardImportResult struct {
+	Row    int    `json:"row"`
+	Front  string `json:"front"`
+	Back   string `json:"back"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	CardID int32  `json:"card_id,omitempty"`
+}
+
+// AnkiImportResponse is the set created from an Anki package
+type AnkiImportResponse struct {
+	Set      db.FlashcardSet `json:"set"`
+	Imported int             `json:"imported"`
+	Skipped  int             `json:"skipped"` // notes with an empty or too long front or back
+}
+
+// SetBundle is a whole set as one versioned json document, for moving sets between instances
+type SetBundle struct {
+	Version    int          `json:"version"`
+	ExportedAt time.Time    `json:"exported_at"`
+	Set        BundleSet    `json:"set"`
+	Cards      []BundleCard `json:"cards"`
+}
+
+// BundleSet is a set's metadata and settings; nil mastery thresholds inherit the class rule
+type BundleSet struct {
+	Name                string `json:"name"`
+	Description         string `json:"description"`
+	MasteryStreak       *int32 `json:"mastery_streak"`
+	MasteryIntervalDays *int32 `json:"mastery_interval_days"`
+	TypoTolerance       int32  `json:"typo_tolerance"`
+	Visibility          string `json:"visibility"`
+}
+
+// BundleCard is one card of a bundle
+type BundleCard struct {
+	Front string        `json:"front"`
+	Back  string        `json:"back"`
+	Media []BundleMedia `json:"media,omitempty"`
+}
+
+// BundleMedia is a link to an image, audio or video file used on one side of a card
+type BundleMedia struct {
+	Side string `json:"side"`
+	URL  string `json:"url"`
+	Type string `json:"type,omitempty"`
+}
+
+// ForkDiffResponse compares a fork with the set it was forked from
+type ForkDiffResponse struct {
+	UpstreamSetID int32          `json:"upstream_set_id"`
+	ForkedAt      time.Time      `json:"forked_at"`
+	New           []ForkDiffCard `json:"new"`
+	Changed       []ForkDiffCard `json:"changed"`
+	Removed       []ForkDiffCard `json:"removed"`
+}
+
+// ForkDiffCard is one card of the diff. New cards only have the upstream side and removed
+// cards only have the fork's
+type ForkDiffCard struct {
+	CardID         int32  `json:"card_id,omitempty"`
+	Front          string `json:"front,omitempty"`
+	Back           string `json:"back,omitempty"`
+	UpstreamCardID int32  `json:"upstream_card_id"`
+	UpstreamFront  string `json:"upstream_front,omitempty"`
+	UpstreamBack   string `json:"upstream_back,omitempty"`
+}
+
+// RevisionsResponse is one page of a set's history, newest first
+type RevisionsResponse struct {
+	Page      int        `json:"page"`
+	PageSize  int        `json:"page_size"`
+	HasMore   bool       `json:"has_more"`
+	Revisions []Revision `json:"revisions"`
+}
+
+// Revision is one logged change to a set or one of its cards. Set changes have no card id,
+// creates have no before and deletes no after
+type Revision struct {
+	ID        int32           `json:"id"`
+	CardID    int32           `json:"card_id,omitempty"`
+	UserID    int32           `json:"user_id,omitempty"`
+	Username  string          `json:"username,omitempty"`
+	Op        string          `json:"op"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// RevisionSet is the part of a set the history tracks, keyed the way revisions store it
+type RevisionSet struct {
+	SetName             string      `json:"set_name"`
+	SetDescription      string      `json:"set_description"`
+	MasteryStreak       pgtype.Int4 `json:"mastery_streak"`
+	MasteryIntervalDays pgtype.Int4 `json:"mastery_interval_days"`
+	TypoTolerance       int32       `json:"typo_tolerance"`
+}
+
+type RevisionCard struct {
+	Front string `json:"front"`
+	Back  string `json:"back"`
+}
+
+// RevisionDiffResponse compares a set as it was after revision From with how it was after
+// revision To, or how it is now when To is left out
+type RevisionDiffResponse struct {
+	From      int32              `json:"from"`
+	To        int32              `json:"to,omitempty"`
+	SetBefore *RevisionSet       `json:"set_before,omitempty"` // only when the settings changed
+	SetAfter  *RevisionSet       `json:"set_after,omitempty"`
+	Added     []RevisionCardDiff `json:"added"`
+	Changed   []RevisionCardDiff `json:"changed"`
+	Removed   []RevisionCardDiff `json:"removed"`
+}
+
+type RevisionCardDiff struct {
+	CardID int32         `json:"card_id"`
+	Before *RevisionCard `json:"before,omitempty"`
+	After  *RevisionCard `json:"after,omitempty"`
+}
+
+// RollbackResponse counts what a rollback changed. Skipped cards had moved to another set
+type RollbackResponse struct {
+	RevisionID  int32 `json:"revision_id"`
+	SetRestored bool  `json:"set_restored"`
+	Restored    int   `json:"restored"`
+	Removed     int   `json:"removed"`
+	Skipped     int   `json:"skipped"`
+}
+
+// ProgressStats are a student's totals for one set, or across all of a class's sets
+type ProgressStats struct {
+	TotalCards    int32            `json:"total_cards"`
+	CardsStudied  int32            `json:"cards_studied"`
+	CardsMastered int32            `json:"cards_mastered"`
+	Correct       int32            `json:"correct"`
+	Attempts      int32            `json:"attempts"`
+	AccuracyPct   int32            `json:"accuracy_pct"`
+	LastStudiedAt pgtype.Timestamp `json:"last_studied_at"`
+	TimeMs        int64            `json:"time_ms"`
+}
+
+// StudentProgress is one student's row of the class progress report
+type StudentProgress struct {
+	UserID    int32  `json:"user_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Username  string `json:"username"`
+	ProgressStats
+	Sets []SetProgress `json:"sets"`
+}
+
+// SetProgress is a student's progress on one set of the class
+type SetProgress struct {
+	SetID   int32  `json:"set_id"`
+	SetName string `json:"set_name"`
+	ProgressStats
+}
+
+// CardDifficulty is one card of the class item analysis. accuracy_pct is over every
+// attempt, struggled_pct over every student in the class
+type CardDifficulty struct {
+	db.ListHardestCardsInAClassRow
+	AccuracyPct  int32 `json:"accuracy_pct"`
+	StruggledPct int32 `json:"struggled_pct"`
+}
+
+// LeaderboardResponse is one page of a windowed leaderboard; the window is [window_start, window_end)
+type LeaderboardResponse struct {
+	WindowStart time.Time          `json:"window_start"`
+	WindowEnd   time.Time          `json:"window_end"`
+	Page        int                `json:"page"`
+	PageSize    int                `json:"page_size"`
+	HasMore     bool               `json:"has_more"`
+	Entries     []LeaderboardEntry `json:"entries"`
+}
+
+// LeaderboardEntry is one ranked user; names are left out where they aren't shown, and
+// the id too when the user is behind a nickname
+type LeaderboardEntry struct {
+	Rank      int    `json:"rank"`
+	UserID    int32  `json:"user_id,omitempty"`
+	FirstName string `json:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty"`
+	Username  string `json:"username"`
+	Score     int32  `json:"score"`
+	Reviews   int32  `json:"reviews"`
+}
+
+// ClassLeaderboardEntry is a row of the lifetime class leaderboard. It keeps the untagged
+// field names the leaderboard has always sent
+type ClassLeaderboardEntry struct {
+	UserID     int32 `json:",omitempty"`
+	FirstName  string
+	LastName   string
+	Username   string
+	ClassScore any
+}
+
+// ClassMember is a row of a class's member list, named as the viewer may see them
+type ClassMember struct {
+	UserID    int32 `json:",omitempty"`
+	ClassID   int32
+	Role      string
+	FirstName string
+	LastName  string
+	Username  string
+}
+
+// per-item outcomes for batch endpoints
+const (
+	statusApplied   string = "applied"
+	statusConflict  string = "conflict"
+	statusCreated   string = "created"
+	statusDuplicate string = "duplicate"
+	statusEnrolled  string = "enrolled"
+	statusError     string = "error"
+	statusForbidden string = "forbidden"
+	statusGone      string = "gone"
+	statusSkipped   string = "skipped"
+)
+
 var errContext error = errors.New("error retrieving from context")
 var errHeader error = errors.New("error retrieving from headers")
+var errLastTeacher error = errors.New("class would be left without a teacher")
+var errUsernameTaken error = errors.New("username is already taken")
+var errSetNotFound error = errors.New("set doesn't exist or isn't visible to the user")
+var errNoAttemptsLeft error = errors.New("no test attempts left")
 
 const (
+	attempt_id        string = "attempt_id"
 	back              string = "back"
+	back_field        string = "back_field"
+	card_separator    string = "card_separator"
 	card_id           string = "card_id"
 	class_description string = "class_description"
 	class_id          string = "class_id"
 	class_name        string = "class_name"
+	count             string = "count"
+	cursor            string = "cursor"
 	correct           string = "correct"
+	dry_run           string = "dry_run"
 	email             string = "email"
+	expires_in_hours  string = "expires_in_hours"
 	first_name        string = "first_name"
+	format            string = "format"
+	fromStr           string = "from"
 	front             string = "front"
+	front_field       string = "front_field"
+	history           string = "history"
 	id                string = "id"
+	is_private        string = "is_private"
 	incorrect         string = "incorrect"
+	inherit           string = "inherit"
+	join_code         string = "join_code"
 	last_name         string = "last_name"
+	mastery_interval  string = "mastery_interval_days"
+	mastery_streak    string = "mastery_streak"
+	opt_in            string = "opt_in"
 	owner             string = "owner"
+	pageStr           string = "page"
+	page_size         string = "page_size"
 	password          string = "password"
+	revision_id       string = "revision_id"
 	roleStr           string = "role"
+	separator         string = "separator"
 	set_description   string = "set_description"
 	set_id            string = "set_id"
 	set_name          string = "set_name"
+	student           string = "student"
 	student_id        string = "student_id"
 	teacher           string = "teacher"
+	test_id           string = "test_id"
 	username          string = "username"
 	token             string = "token"
+	toStr             string = "to"
+	user              string = "user"
+	user_id           string = "user_id"
+	visibility        string = "visibility"
+	typo_tolerance    string = "typo_tolerance"
+	window            string = "window"
 )
 
 func logAndSendError(w http.ResponseWriter, err error, msg string, statusCode int) {
@@ -104,6 +588,55 @@ func getHeaderVals(r *http.Request, headers ...string) (map[string]string, error
 	return middleware.GetHeaderVals(r, headers...)
 }
 
+const (
+	defaultPageSize int = 25
+	maxPageSize     int = 100
+)
+
+// getPage reads the optional page (from 1) and page_size headers of a paged list
+func getPage(r *http.Request) (page, pageSize int, err error) {
+	page, pageSize = 1, defaultPageSize
+
+	if vals, err := getHeaderVals(r, pageStr); err == nil {
+		page, err = strconv.Atoi(vals[pageStr])
+		if err != nil || page < 1 {
+			return 0, 0, errors.New("page must be a positive number")
+		}
+	}
+
+	if vals, err := getHeaderVals(r, page_size); err == nil {
+		pageSize, err = strconv.Atoi(vals[page_size])
+		if err != nil || pageSize < 1 || pageSize > maxPageSize {
+			return 0, 0, fmt.Errorf("page_size must be between 1 and %d", maxPageSize)
+		}
+	}
+
+	// the offset goes to the query as an int32
+	if page-1 > math.MaxInt32/pageSize {
+		return 0, 0, errors.New("page is too far out")
+	}
+
+	return page, pageSize, nil
+}
+
+// a mastery threshold header is either a non-negative number (0 turns the criterion off)
+// or "inherit", which clears the override so the class/default rule applies
+func getMasteryThreshold(val string) (threshold pgtype.Int4, err error) {
+	if val == inherit {
+		return pgtype.Int4{}, nil
+	}
+
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		return threshold, err
+	}
+	if n < 0 || n > math.MaxInt32 {
+		return threshold, errors.New("threshold out of range")
+	}
+
+	return pgtype.Int4{Int32: int32(n), Valid: true}, nil
+}
+
 func getQueryConnAndContext(r *http.Request, h *DBHandler) (query *db.Queries, ctx context.Context, conn *pgxpool.Conn, err error) {
 	ctx = r.Context()
 
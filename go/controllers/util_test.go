@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestGetPage(t *testing.T) {
+	tests := []struct {
+		name         string
+		page         string
+		pageSize     string
+		wantPage     int
+		wantPageSize int
+		wantErr      bool
+	}{
+		{name: "defaults", wantPage: 1, wantPageSize: defaultPageSize},
+		{name: "both set", page: "3", pageSize: "50", wantPage: 3, wantPageSize: 50},
+		{name: "zero page", page: "0", wantErr: true},
+		{name: "page size over the max", pageSize: strconv.Itoa(maxPageSize + 1), wantErr: true},
+		{name: "last page that fits", page: "21474837", pageSize: "100", wantPage: 21474837, wantPageSize: 100},
+		{name: "offset past int32", page: "21474838", pageSize: "100", wantErr: true},
+		{name: "huge page", page: "9223372036854775807", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.page != "" {
+				r.Header.Set(pageStr, tt.page)
+			}
+			if tt.pageSize != "" {
+				r.Header.Set(page_size, tt.pageSize)
+			}
+
+			page, pageSize, err := getPage(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (page != tt.wantPage || pageSize != tt.wantPageSize) {
+				t.Errorf("got page %d size %d, want %d %d", page, pageSize, tt.wantPage, tt.wantPageSize)
+			}
+		})
+	}
+}
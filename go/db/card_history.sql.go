@@ -7,8 +7,67 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const getCardForTypedAnswer = `-- name: GetCardForTypedAnswer :one
+SELECT f.back, f.set_id, s.typo_tolerance FROM flashcards AS f
+JOIN flashcard_sets AS s ON s.id = f.set_id
+WHERE f.id = $1
+`
+
+type GetCardForTypedAnswerRow struct {
+	Back          string
+	SetID         int32
+	TypoTolerance int32
+}
+
+func (q *Queries) GetCardForTypedAnswer(ctx context.Context, id int32) (GetCardForTypedAnswerRow, error) {
+	row := q.db.QueryRow(ctx, getCardForTypedAnswer, id)
+	var i GetCardForTypedAnswerRow
+	err := row.Scan(&i.Back, &i.SetID, &i.TypoTolerance)
+	return i, err
+}
+
+const getCardSchedule = `-- name: GetCardSchedule :one
+SELECT ease_factor, interval_days, repetitions, lapses, correct_streak, due_at,
+COALESCE($1::timestamptz::timestamp < last_reviewed_at, false)::bool AS stale
+FROM card_history
+WHERE user_id = $2 AND card_id = $3
+`
+
+type GetCardScheduleParams struct {
+	ReviewedAt pgtype.Timestamptz
+	UserID     int32
+	CardID     int32
+}
+
+type GetCardScheduleRow struct {
+	EaseFactor    float64
+	IntervalDays  int32
+	Repetitions   int32
+	Lapses        int32
+	CorrectStreak int32
+	DueAt         pgtype.Timestamp
+	Stale         bool
+}
+
+func (q *Queries) GetCardSchedule(ctx context.Context, arg GetCardScheduleParams) (GetCardScheduleRow, error) {
+	row := q.db.QueryRow(ctx, getCardSchedule, arg.ReviewedAt, arg.UserID, arg.CardID)
+	var i GetCardScheduleRow
+	err := row.Scan(
+		&i.EaseFactor,
+		&i.IntervalDays,
+		&i.Repetitions,
+		&i.Lapses,
+		&i.CorrectStreak,
+		&i.DueAt,
+		&i.Stale,
+	)
+	return i, err
+}
+
 const getCardScore = `-- name: GetCardScore :one
 SELECT score AS correct, (times_attempted - score) AS incorrect, (score - times_attempted) AS net_score, times_attempted FROM card_history
 WHERE user_id = $1 AND card_id = $2
@@ -60,6 +119,70 @@ func (q *Queries) GetCardsStudied(ctx context.Context, userID int32) (int64, err
 	return count, err
 }
 
+const getMasteryCountsInASet = `-- name: GetMasteryCountsInASet :one
+SELECT COUNT(flashcards.id) FILTER (WHERE card_history.is_mastered) AS mastered,
+COUNT(flashcards.id) FILTER (WHERE NOT card_history.is_mastered) AS learning,
+COUNT(flashcards.id) FILTER (WHERE card_history.card_id IS NULL) AS new_cards
+FROM flashcards
+LEFT JOIN card_history ON (card_history.card_id = flashcards.id AND card_history.user_id = $1)
+WHERE flashcards.set_id = $2
+`
+
+type GetMasteryCountsInASetParams struct {
+	UserID int32
+	SetID  int32
+}
+
+type GetMasteryCountsInASetRow struct {
+	Mastered int64
+	Learning int64
+	NewCards int64
+}
+
+func (q *Queries) GetMasteryCountsInASet(ctx context.Context, arg GetMasteryCountsInASetParams) (GetMasteryCountsInASetRow, error) {
+	row := q.db.QueryRow(ctx, getMasteryCountsInASet, arg.UserID, arg.SetID)
+	var i GetMasteryCountsInASetRow
+	err := row.Scan(&i.Mastered, &i.Learning, &i.NewCards)
+	return i, err
+}
+
+const getMasteryRule = `-- name: GetMasteryRule :one
+SELECT COALESCE(flashcard_sets.mastery_streak, MAX(classes.mastery_streak), $1::int)::int AS streak,
+COALESCE(flashcard_sets.mastery_interval_days, MAX(classes.mastery_interval_days), $2::int)::int AS interval_days
+FROM flashcards
+JOIN flashcard_sets ON flashcards.set_id = flashcard_sets.id
+LEFT JOIN class_set ON flashcard_sets.id = class_set.set_id
+LEFT JOIN class_user ON (class_set.class_id = class_user.class_id AND class_user.user_id = $3)
+LEFT JOIN classes ON class_user.class_id = classes.id
+WHERE flashcards.id = $4
+GROUP BY flashcard_sets.id
+`
+
+type GetMasteryRuleParams struct {
+	DefaultStreak       int32
+	DefaultIntervalDays int32
+	UserID              int32
+	CardID              int32
+}
+
+type GetMasteryRuleRow struct {
+	Streak       int32
+	IntervalDays int32
+}
+
+// set overrides win over class overrides; across classes the strictest (largest) threshold applies
+func (q *Queries) GetMasteryRule(ctx context.Context, arg GetMasteryRuleParams) (GetMasteryRuleRow, error) {
+	row := q.db.QueryRow(ctx, getMasteryRule,
+		arg.DefaultStreak,
+		arg.DefaultIntervalDays,
+		arg.UserID,
+		arg.CardID,
+	)
+	var i GetMasteryRuleRow
+	err := row.Scan(&i.Streak, &i.IntervalDays)
+	return i, err
+}
+
 const getScoresInASet = `-- name: GetScoresInASet :many
 SELECT set_name, score AS correct, (times_attempted - score) AS incorrect, score AS net_score, times_attempted 
 FROM card_history 
@@ -117,6 +240,214 @@ func (q *Queries) GetTotalCardViews(ctx context.Context, userID int32) (interfac
 	return coalesce, err
 }
 
+const listCardSchedulesInASet = `-- name: ListCardSchedulesInASet :many
+SELECT card_id, ease_factor, interval_days, repetitions, lapses, due_at FROM card_history
+JOIN flashcards ON card_history.card_id = flashcards.id
+WHERE user_id = $1 AND set_id = $2
+`
+
+type ListCardSchedulesInASetParams struct {
+	UserID int32
+	SetID  int32
+}
+
+type ListCardSchedulesInASetRow struct {
+	CardID       int32
+	EaseFactor   float64
+	IntervalDays int32
+	Repetitions  int32
+	Lapses       int32
+	DueAt        pgtype.Timestamp
+}
+
+func (q *Queries) ListCardSchedulesInASet(ctx context.Context, arg ListCardSchedulesInASetParams) ([]ListCardSchedulesInASetRow, error) {
+	rows, err := q.db.Query(ctx, listCardSchedulesInASet, arg.UserID, arg.SetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCardSchedulesInASetRow
+	for rows.Next() {
+		var i ListCardSchedulesInASetRow
+		if err := rows.Scan(
+			&i.CardID,
+			&i.EaseFactor,
+			&i.IntervalDays,
+			&i.Repetitions,
+			&i.Lapses,
+			&i.DueAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDueCards = `-- name: ListDueCards :many
+SELECT flashcards.id, front, back, flashcards.set_id, set_name, due_at, interval_days, lapses
+FROM set_user
+JOIN flashcard_sets ON set_user.set_id = flashcard_sets.id
+JOIN flashcards ON flashcard_sets.id = flashcards.set_id
+LEFT JOIN card_history ON (card_history.card_id = flashcards.id AND card_history.user_id = set_user.user_id)
+WHERE set_user.user_id = $1 AND (due_at IS NULL OR due_at <= LOCALTIMESTAMP(2))
+ORDER BY due_at NULLS LAST, flashcards.id
+`
+
+type ListDueCardsRow struct {
+	ID           int32
+	Front        string
+	Back         string
+	SetID        int32
+	SetName      string
+	DueAt        pgtype.Timestamp
+	IntervalDays pgtype.Int4
+	Lapses       pgtype.Int4
+}
+
+func (q *Queries) ListDueCards(ctx context.Context, userID int32) ([]ListDueCardsRow, error) {
+	rows, err := q.db.Query(ctx, listDueCards, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDueCardsRow
+	for rows.Next() {
+		var i ListDueCardsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Front,
+			&i.Back,
+			&i.SetID,
+			&i.SetName,
+			&i.DueAt,
+			&i.IntervalDays,
+			&i.Lapses,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMasteryCountsOfAUser = `-- name: ListMasteryCountsOfAUser :many
+SELECT flashcard_sets.id AS set_id, set_name,
+COUNT(flashcards.id) FILTER (WHERE card_history.is_mastered) AS mastered,
+COUNT(flashcards.id) FILTER (WHERE NOT card_history.is_mastered) AS learning,
+COUNT(flashcards.id) FILTER (WHERE card_history.card_id IS NULL) AS new_cards
+FROM set_user
+JOIN flashcard_sets ON set_user.set_id = flashcard_sets.id
+JOIN flashcards ON flashcard_sets.id = flashcards.set_id
+LEFT JOIN card_history ON (card_history.card_id = flashcards.id AND card_history.user_id = set_user.user_id)
+WHERE set_user.user_id = $1
+GROUP BY flashcard_sets.id, set_name ORDER BY set_name
+`
+
+type ListMasteryCountsOfAUserRow struct {
+	SetID    int32
+	SetName  string
+	Mastered int64
+	Learning int64
+	NewCards int64
+}
+
+func (q *Queries) ListMasteryCountsOfAUser(ctx context.Context, userID int32) ([]ListMasteryCountsOfAUserRow, error) {
+	rows, err := q.db.Query(ctx, listMasteryCountsOfAUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMasteryCountsOfAUserRow
+	for rows.Next() {
+		var i ListMasteryCountsOfAUserRow
+		if err := rows.Scan(
+			&i.SetID,
+			&i.SetName,
+			&i.Mastered,
+			&i.Learning,
+			&i.NewCards,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recomputeMasteryInASet = `-- name: RecomputeMasteryInASet :exec
+UPDATE card_history SET is_mastered = (
+  ($1::int > 0 AND correct_streak >= $1::int)
+  OR ($2::int > 0 AND interval_days >= $2::int)
+)
+WHERE card_id IN (SELECT id FROM flashcards WHERE set_id = $3)
+`
+
+type RecomputeMasteryInASetParams struct {
+	Streak       int32
+	IntervalDays int32
+	SetID        int32
+}
+
+// a set override changes the rule for everyone studying it, so settle existing rows right away
+func (q *Queries) RecomputeMasteryInASet(ctx context.Context, arg RecomputeMasteryInASetParams) error {
+	_, err := q.db.Exec(ctx, recomputeMasteryInASet, arg.Streak, arg.IntervalDays, arg.SetID)
+	return err
+}
+
+const updateCardSchedule = `-- name: UpdateCardSchedule :exec
+UPDATE card_history SET ease_factor = $1, interval_days = $2, repetitions = $3,
+lapses = $4, correct_streak = $5, is_mastered = $6,
+due_at = COALESCE($7::timestamptz::timestamp, LOCALTIMESTAMP(2)) + ($8::int * INTERVAL '1 minute'),
+last_reviewed_at = COALESCE($7::timestamptz::timestamp, LOCALTIMESTAMP(2)),
+last_grade = $9, last_response_ms = $10, last_answer = $11
+WHERE user_id = $12 AND card_id = $13
+`
+
+type UpdateCardScheduleParams struct {
+	EaseFactor     float64
+	IntervalDays   int32
+	Repetitions    int32
+	Lapses         int32
+	CorrectStreak  int32
+	IsMastered     bool
+	ReviewedAt     pgtype.Timestamptz
+	DueInMinutes   int32
+	LastGrade      pgtype.Text
+	LastResponseMs pgtype.Int4
+	LastAnswer     pgtype.Text
+	UserID         int32
+	CardID         int32
+}
+
+func (q *Queries) UpdateCardSchedule(ctx context.Context, arg UpdateCardScheduleParams) error {
+	_, err := q.db.Exec(ctx, updateCardSchedule,
+		arg.EaseFactor,
+		arg.IntervalDays,
+		arg.Repetitions,
+		arg.Lapses,
+		arg.CorrectStreak,
+		arg.IsMastered,
+		arg.ReviewedAt,
+		arg.DueInMinutes,
+		arg.LastGrade,
+		arg.LastResponseMs,
+		arg.LastAnswer,
+		arg.UserID,
+		arg.CardID,
+	)
+	return err
+}
+
 const upsertCorrectFlashcardScore = `-- name: UpsertCorrectFlashcardScore :exec
 INSERT INTO card_history (user_id, card_id, score) VALUES ($1, $2, 1) 
 ON CONFLICT (user_id, card_id) DO 
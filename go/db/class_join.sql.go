@@ -0,0 +1,169 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: class_join.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createClassInvite = `-- name: CreateClassInvite :one
+INSERT INTO class_invites (token, class_id, expires_at)
+VALUES ($1, $2, LOCALTIMESTAMP(2) + make_interval(hours => $3::int))
+RETURNING token, class_id, expires_at, created_at
+`
+
+type CreateClassInviteParams struct {
+	Token   string
+	ClassID int32
+	Hours   int32
+}
+
+func (q *Queries) CreateClassInvite(ctx context.Context, arg CreateClassInviteParams) (ClassInvite, error) {
+	row := q.db.QueryRow(ctx, createClassInvite, arg.Token, arg.ClassID, arg.Hours)
+	var i ClassInvite
+	err := row.Scan(
+		&i.Token,
+		&i.ClassID,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteClassInvite = `-- name: DeleteClassInvite :exec
+DELETE FROM class_invites WHERE token = $1 AND class_id = $2
+`
+
+type DeleteClassInviteParams struct {
+	Token   string
+	ClassID int32
+}
+
+func (q *Queries) DeleteClassInvite(ctx context.Context, arg DeleteClassInviteParams) error {
+	_, err := q.db.Exec(ctx, deleteClassInvite, arg.Token, arg.ClassID)
+	return err
+}
+
+const getClassIdByInvite = `-- name: GetClassIdByInvite :one
+SELECT class_id FROM class_invites WHERE token = $1 AND expires_at > LOCALTIMESTAMP(2)
+`
+
+func (q *Queries) GetClassIdByInvite(ctx context.Context, token string) (int32, error) {
+	row := q.db.QueryRow(ctx, getClassIdByInvite, token)
+	var class_id int32
+	err := row.Scan(&class_id)
+	return class_id, err
+}
+
+const getClassIdByJoinCode = `-- name: GetClassIdByJoinCode :one
+SELECT class_id FROM class_join_codes
+WHERE code = UPPER($1) AND enabled AND (expires_at IS NULL OR expires_at > LOCALTIMESTAMP(2))
+`
+
+func (q *Queries) GetClassIdByJoinCode(ctx context.Context, code interface{}) (int32, error) {
+	row := q.db.QueryRow(ctx, getClassIdByJoinCode, code)
+	var class_id int32
+	err := row.Scan(&class_id)
+	return class_id, err
+}
+
+const getJoinCode = `-- name: GetJoinCode :one
+SELECT class_id, code, enabled, expires_at, updated_at FROM class_join_codes WHERE class_id = $1
+`
+
+func (q *Queries) GetJoinCode(ctx context.Context, classID int32) (ClassJoinCode, error) {
+	row := q.db.QueryRow(ctx, getJoinCode, classID)
+	var i ClassJoinCode
+	err := row.Scan(
+		&i.ClassID,
+		&i.Code,
+		&i.Enabled,
+		&i.ExpiresAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listClassInvites = `-- name: ListClassInvites :many
+SELECT token, class_id, expires_at, created_at FROM class_invites WHERE class_id = $1 AND expires_at > LOCALTIMESTAMP(2) ORDER BY created_at DESC
+`
+
+func (q *Queries) ListClassInvites(ctx context.Context, classID int32) ([]ClassInvite, error) {
+	rows, err := q.db.Query(ctx, listClassInvites, classID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClassInvite
+	for rows.Next() {
+		var i ClassInvite
+		if err := rows.Scan(
+			&i.Token,
+			&i.ClassID,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const regenerateJoinCode = `-- name: RegenerateJoinCode :one
+INSERT INTO class_join_codes (class_id, expires_at) VALUES ($1, $2::timestamptz::timestamp)
+ON CONFLICT (class_id) DO UPDATE SET code = EXCLUDED.code, enabled = true, expires_at = EXCLUDED.expires_at, updated_at = LOCALTIMESTAMP(2)
+RETURNING class_id, code, enabled, expires_at, updated_at
+`
+
+type RegenerateJoinCodeParams struct {
+	ClassID   int32
+	ExpiresAt pgtype.Timestamptz
+}
+
+// regenerating replaces the code, which also re-enables it
+func (q *Queries) RegenerateJoinCode(ctx context.Context, arg RegenerateJoinCodeParams) (ClassJoinCode, error) {
+	row := q.db.QueryRow(ctx, regenerateJoinCode, arg.ClassID, arg.ExpiresAt)
+	var i ClassJoinCode
+	err := row.Scan(
+		&i.ClassID,
+		&i.Code,
+		&i.Enabled,
+		&i.ExpiresAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateJoinCode = `-- name: UpdateJoinCode :one
+UPDATE class_join_codes SET enabled = $1, expires_at = $2::timestamptz::timestamp, updated_at = LOCALTIMESTAMP(2)
+WHERE class_id = $3
+RETURNING class_id, code, enabled, expires_at, updated_at
+`
+
+type UpdateJoinCodeParams struct {
+	Enabled   bool
+	ExpiresAt pgtype.Timestamptz
+	ClassID   int32
+}
+
+func (q *Queries) UpdateJoinCode(ctx context.Context, arg UpdateJoinCodeParams) (ClassJoinCode, error) {
+	row := q.db.QueryRow(ctx, updateJoinCode, arg.Enabled, arg.ExpiresAt, arg.ClassID)
+	var i ClassJoinCode
+	err := row.Scan(
+		&i.ClassID,
+		&i.Code,
+		&i.Enabled,
+		&i.ExpiresAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
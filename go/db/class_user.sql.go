@@ -75,10 +75,73 @@ func (q *Queries) ListClassesOfAUser(ctx context.Context, userID int32) ([]ListC
 }
 
 const listMembersOfAClass = `-- name: ListMembersOfAClass :many
-SELECT user_id, class_id, role, first_name, last_name, username FROM class_user JOIN users ON class_user.user_id = users.id WHERE class_id = $1 ORDER BY last_name, first_name
+SELECT class_user.user_id, class_user.class_id, class_user.role, users.first_name, users.last_name, users.username,
+users.leaderboard_visibility, users.nickname_seed, classes.leaderboard_visibility AS class_visibility
+FROM class_user
+JOIN users ON class_user.user_id = users.id
+JOIN classes ON class_user.class_id = classes.id
+WHERE class_user.class_id = $1 AND ($2::bool OR class_user.user_id = $3
+  OR (users.leaderboard_visibility <> 'hidden' AND classes.leaderboard_visibility <> 'hidden'))
+ORDER BY users.last_name, users.first_name
 `
 
+type ListMembersOfAClassParams struct {
+	ClassID   int32
+	IsTeacher bool
+	ViewerID  int32
+}
+
 type ListMembersOfAClassRow struct {
+	UserID                int32
+	ClassID               int32
+	Role                  string
+	FirstName             string
+	LastName              string
+	Username              string
+	LeaderboardVisibility string
+	NicknameSeed          int32
+	ClassVisibility       string
+}
+
+// Teachers (is_teacher) see every member; everyone else misses members who are hidden, unless it's themselves
+func (q *Queries) ListMembersOfAClass(ctx context.Context, arg ListMembersOfAClassParams) ([]ListMembersOfAClassRow, error) {
+	rows, err := q.db.Query(ctx, listMembersOfAClass, arg.ClassID, arg.IsTeacher, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMembersOfAClassRow
+	for rows.Next() {
+		var i ListMembersOfAClassRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.ClassID,
+			&i.Role,
+			&i.FirstName,
+			&i.LastName,
+			&i.Username,
+			&i.LeaderboardVisibility,
+			&i.NicknameSeed,
+			&i.ClassVisibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStudentsOfAClass = `-- name: ListStudentsOfAClass :many
+SELECT user_id, class_id, role, first_name, last_name, username
+FROM class_user JOIN users ON class_user.user_id = users.id
+WHERE class_id = $1 AND role = 'student'
+ORDER BY last_name, first_name
+`
+
+type ListStudentsOfAClassRow struct {
 	UserID    int32
 	ClassID   int32
 	Role      string
@@ -87,15 +150,58 @@ type ListMembersOfAClassRow struct {
 	Username  string
 }
 
-func (q *Queries) ListMembersOfAClass(ctx context.Context, classID int32) ([]ListMembersOfAClassRow, error) {
-	rows, err := q.db.Query(ctx, listMembersOfAClass, classID)
+func (q *Queries) ListStudentsOfAClass(ctx context.Context, classID int32) ([]ListStudentsOfAClassRow, error) {
+	rows, err := q.db.Query(ctx, listStudentsOfAClass, classID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []ListMembersOfAClassRow
+	var items []ListStudentsOfAClassRow
 	for rows.Next() {
-		var i ListMembersOfAClassRow
+		var i ListStudentsOfAClassRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.ClassID,
+			&i.Role,
+			&i.FirstName,
+			&i.LastName,
+			&i.Username,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTeachersOfAClass = `-- name: ListTeachersOfAClass :many
+SELECT user_id, class_id, role, first_name, last_name, username
+FROM class_user JOIN users ON class_user.user_id = users.id
+WHERE class_id = $1 AND role = 'teacher'
+ORDER BY last_name, first_name
+`
+
+type ListTeachersOfAClassRow struct {
+	UserID    int32
+	ClassID   int32
+	Role      string
+	FirstName string
+	LastName  string
+	Username  string
+}
+
+func (q *Queries) ListTeachersOfAClass(ctx context.Context, classID int32) ([]ListTeachersOfAClassRow, error) {
+	rows, err := q.db.Query(ctx, listTeachersOfAClass, classID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTeachersOfAClassRow
+	for rows.Next() {
+		var i ListTeachersOfAClassRow
 		if err := rows.Scan(
 			&i.UserID,
 			&i.ClassID,
@@ -113,3 +219,46 @@ func (q *Queries) ListMembersOfAClass(ctx context.Context, classID int32) ([]Lis
 	}
 	return items, nil
 }
+
+const lockTeachersOfAClass = `-- name: LockTeachersOfAClass :many
+SELECT user_id FROM class_user WHERE class_id = $1 AND role = 'teacher' FOR UPDATE
+`
+
+// locks the teacher rows so concurrent demotions/removals can't both pass the last-teacher check
+func (q *Queries) LockTeachersOfAClass(ctx context.Context, classID int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, lockTeachersOfAClass, classID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var user_id int32
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateClassMemberRole = `-- name: UpdateClassMemberRole :execrows
+UPDATE class_user SET role = $1 WHERE class_id = $2 AND user_id = $3
+`
+
+type UpdateClassMemberRoleParams struct {
+	Role    string
+	ClassID int32
+	UserID  int32
+}
+
+func (q *Queries) UpdateClassMemberRole(ctx context.Context, arg UpdateClassMemberRoleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateClassMemberRole, arg.Role, arg.ClassID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -7,10 +7,12 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createClass = `-- name: CreateClass :one
-INSERT INTO classes (class_name, class_description) VALUES ($1, $2) RETURNING id, class_name, class_description, created_at, updated_at
+INSERT INTO classes (class_name, class_description) VALUES ($1, $2) RETURNING id, class_name, class_description, mastery_streak, mastery_interval_days, leaderboard_visibility, created_at, updated_at
 `
 
 type CreateClassParams struct {
@@ -25,6 +27,9 @@ func (q *Queries) CreateClass(ctx context.Context, arg CreateClassParams) (Class
 		&i.ID,
 		&i.ClassName,
 		&i.ClassDescription,
+		&i.MasteryStreak,
+		&i.MasteryIntervalDays,
+		&i.LeaderboardVisibility,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -41,7 +46,7 @@ func (q *Queries) DeleteClass(ctx context.Context, id int32) error {
 }
 
 const getClassById = `-- name: GetClassById :one
-SELECT id, class_name, class_description, created_at, updated_at FROM classes WHERE id = $1
+SELECT id, class_name, class_description, mastery_streak, mastery_interval_days, leaderboard_visibility, created_at, updated_at FROM classes WHERE id = $1
 `
 
 func (q *Queries) GetClassById(ctx context.Context, id int32) (Class, error) {
@@ -51,6 +56,9 @@ func (q *Queries) GetClassById(ctx context.Context, id int32) (Class, error) {
 		&i.ID,
 		&i.ClassName,
 		&i.ClassDescription,
+		&i.MasteryStreak,
+		&i.MasteryIntervalDays,
+		&i.LeaderboardVisibility,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -58,26 +66,41 @@ func (q *Queries) GetClassById(ctx context.Context, id int32) (Class, error) {
 }
 
 const getClassLeaderboard = `-- name: GetClassLeaderboard :many
-SELECT class_user.user_id, users.first_name, users.last_name, users.username, COALESCE(SUM(set_score),0) AS class_score FROM classes 
+SELECT class_user.user_id, users.first_name, users.last_name, users.username,
+COALESCE(SUM(set_score) FILTER (WHERE NOT set_user.is_private OR $1::bool OR class_user.user_id = $2), 0) AS class_score,
+users.leaderboard_visibility, users.nickname_seed, classes.leaderboard_visibility AS class_visibility
+FROM classes
 JOIN class_user ON classes.id = class_user.class_id 
 JOIN class_set ON classes.id = class_set.class_id
 JOIN set_user ON (class_user.user_id = set_user.user_id AND class_set.set_id = set_user.set_id)
 JOIN users ON class_user.user_id = users.id
-WHERE classes.id = $1
-GROUP BY class_user.user_id, users.first_name, users.last_name, users.username
-ORDER BY COALESCE(SUM(set_score),0) DESC
+WHERE classes.id = $3 AND ($1::bool OR class_user.user_id = $2
+  OR (users.leaderboard_visibility <> 'hidden' AND classes.leaderboard_visibility <> 'hidden'))
+GROUP BY class_user.user_id, users.first_name, users.last_name, users.username, users.leaderboard_visibility, users.nickname_seed, classes.leaderboard_visibility
+ORDER BY class_score DESC, class_user.user_id
 `
 
+type GetClassLeaderboardParams struct {
+	IsTeacher bool
+	ViewerID  int32
+	ClassID   int32
+}
+
 type GetClassLeaderboardRow struct {
-	UserID     int32
-	FirstName  string
-	LastName   string
-	Username   string
-	ClassScore interface{}
+	UserID                int32
+	FirstName             string
+	LastName              string
+	Username              string
+	ClassScore            interface{}
+	LeaderboardVisibility string
+	NicknameSeed          int32
+	ClassVisibility       string
 }
 
-func (q *Queries) GetClassLeaderboard(ctx context.Context, id int32) ([]GetClassLeaderboardRow, error) {
-	rows, err := q.db.Query(ctx, getClassLeaderboard, id)
+// lifetime scores. Teachers (is_teacher) see everything; everyone else misses the scores of
+// sets other members marked private and members who are hidden, unless it's themselves
+func (q *Queries) GetClassLeaderboard(ctx context.Context, arg GetClassLeaderboardParams) ([]GetClassLeaderboardRow, error) {
+	rows, err := q.db.Query(ctx, getClassLeaderboard, arg.IsTeacher, arg.ViewerID, arg.ClassID)
 	if err != nil {
 		return nil, err
 	}
@@ -91,6 +114,9 @@ func (q *Queries) GetClassLeaderboard(ctx context.Context, id int32) ([]GetClass
 			&i.LastName,
 			&i.Username,
 			&i.ClassScore,
+			&i.LeaderboardVisibility,
+			&i.NicknameSeed,
+			&i.ClassVisibility,
 		); err != nil {
 			return nil, err
 		}
@@ -103,7 +129,7 @@ func (q *Queries) GetClassLeaderboard(ctx context.Context, id int32) ([]GetClass
 }
 
 const listClasses = `-- name: ListClasses :many
-SELECT id, class_name, class_description, created_at, updated_at FROM classes ORDER BY class_name
+SELECT id, class_name, class_description, mastery_streak, mastery_interval_days, leaderboard_visibility, created_at, updated_at FROM classes ORDER BY class_name
 `
 
 func (q *Queries) ListClasses(ctx context.Context) ([]Class, error) {
@@ -119,6 +145,9 @@ func (q *Queries) ListClasses(ctx context.Context) ([]Class, error) {
 			&i.ID,
 			&i.ClassName,
 			&i.ClassDescription,
+			&i.MasteryStreak,
+			&i.MasteryIntervalDays,
+			&i.LeaderboardVisibility,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -148,6 +177,35 @@ func (q *Queries) UpdateClassDescription(ctx context.Context, arg UpdateClassDes
 	return class_description, err
 }
 
+const updateClassLeaderboardVisibility = `-- name: UpdateClassLeaderboardVisibility :exec
+UPDATE classes SET leaderboard_visibility = $1, updated_at = LOCALTIMESTAMP(2) WHERE id = $2
+`
+
+type UpdateClassLeaderboardVisibilityParams struct {
+	LeaderboardVisibility string
+	ID                    int32
+}
+
+func (q *Queries) UpdateClassLeaderboardVisibility(ctx context.Context, arg UpdateClassLeaderboardVisibilityParams) error {
+	_, err := q.db.Exec(ctx, updateClassLeaderboardVisibility, arg.LeaderboardVisibility, arg.ID)
+	return err
+}
+
+const updateClassMasteryRule = `-- name: UpdateClassMasteryRule :exec
+UPDATE classes SET mastery_streak = $1, mastery_interval_days = $2, updated_at = LOCALTIMESTAMP(2) WHERE id = $3
+`
+
+type UpdateClassMasteryRuleParams struct {
+	MasteryStreak       pgtype.Int4
+	MasteryIntervalDays pgtype.Int4
+	ID                  int32
+}
+
+func (q *Queries) UpdateClassMasteryRule(ctx context.Context, arg UpdateClassMasteryRuleParams) error {
+	_, err := q.db.Exec(ctx, updateClassMasteryRule, arg.MasteryStreak, arg.MasteryIntervalDays, arg.ID)
+	return err
+}
+
 const updateClassName = `-- name: UpdateClassName :one
 UPDATE classes SET class_name = $1, updated_at = LOCALTIMESTAMP(2) WHERE id = $2 RETURNING class_name
 `
@@ -7,10 +7,12 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createFlashcardSet = `-- name: CreateFlashcardSet :one
-INSERT INTO flashcard_sets (set_name, set_description) VALUES ($1, $2) RETURNING id, set_name, set_description, created_at, updated_at
+INSERT INTO flashcard_sets (set_name, set_description) VALUES ($1, $2) RETURNING id, set_name, set_description, mastery_streak, mastery_interval_days, typo_tolerance, visibility, share_token, forked_from, forked_at, created_at, updated_at
 `
 
 type CreateFlashcardSetParams struct {
@@ -25,6 +27,13 @@ func (q *Queries) CreateFlashcardSet(ctx context.Context, arg CreateFlashcardSet
 		&i.ID,
 		&i.SetName,
 		&i.SetDescription,
+		&i.MasteryStreak,
+		&i.MasteryIntervalDays,
+		&i.TypoTolerance,
+		&i.Visibility,
+		&i.ShareToken,
+		&i.ForkedFrom,
+		&i.ForkedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -41,7 +50,7 @@ func (q *Queries) DeleteFlashcardSet(ctx context.Context, id int32) error {
 }
 
 const getFlashcardSetById = `-- name: GetFlashcardSetById :one
-SELECT id, set_name, set_description, created_at, updated_at FROM flashcard_sets WHERE id = $1
+SELECT id, set_name, set_description, mastery_streak, mastery_interval_days, typo_tolerance, visibility, share_token, forked_from, forked_at, created_at, updated_at FROM flashcard_sets WHERE id = $1
 `
 
 func (q *Queries) GetFlashcardSetById(ctx context.Context, id int32) (FlashcardSet, error) {
@@ -51,42 +60,19 @@ func (q *Queries) GetFlashcardSetById(ctx context.Context, id int32) (FlashcardS
 		&i.ID,
 		&i.SetName,
 		&i.SetDescription,
+		&i.MasteryStreak,
+		&i.MasteryIntervalDays,
+		&i.TypoTolerance,
+		&i.Visibility,
+		&i.ShareToken,
+		&i.ForkedFrom,
+		&i.ForkedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
-const listFlashcardSets = `-- name: ListFlashcardSets :many
-SELECT id, set_name, set_description, created_at, updated_at FROM flashcard_sets ORDER BY set_name
-`
-
-func (q *Queries) ListFlashcardSets(ctx context.Context) ([]FlashcardSet, error) {
-	rows, err := q.db.Query(ctx, listFlashcardSets)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []FlashcardSet
-	for rows.Next() {
-		var i FlashcardSet
-		if err := rows.Scan(
-			&i.ID,
-			&i.SetName,
-			&i.SetDescription,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
-}
-
 const updateFlashcardSetDescription = `-- name: UpdateFlashcardSetDescription :one
 UPDATE flashcard_sets SET set_description = $1, updated_at = LOCALTIMESTAMP(2) WHERE id = $2 RETURNING set_description
 `
@@ -103,6 +89,21 @@ func (q *Queries) UpdateFlashcardSetDescription(ctx context.Context, arg UpdateF
 	return set_description, err
 }
 
+const updateFlashcardSetMasteryRule = `-- name: UpdateFlashcardSetMasteryRule :exec
+UPDATE flashcard_sets SET mastery_streak = $1, mastery_interval_days = $2, updated_at = LOCALTIMESTAMP(2) WHERE id = $3
+`
+
+type UpdateFlashcardSetMasteryRuleParams struct {
+	MasteryStreak       pgtype.Int4
+	MasteryIntervalDays pgtype.Int4
+	ID                  int32
+}
+
+func (q *Queries) UpdateFlashcardSetMasteryRule(ctx context.Context, arg UpdateFlashcardSetMasteryRuleParams) error {
+	_, err := q.db.Exec(ctx, updateFlashcardSetMasteryRule, arg.MasteryStreak, arg.MasteryIntervalDays, arg.ID)
+	return err
+}
+
 const updateFlashcardSetName = `-- name: UpdateFlashcardSetName :one
 UPDATE flashcard_sets SET set_name = $1, updated_at = LOCALTIMESTAMP(2) WHERE id = $2 RETURNING set_name
 `
@@ -119,6 +120,47 @@ func (q *Queries) UpdateFlashcardSetName(ctx context.Context, arg UpdateFlashcar
 	return set_name, err
 }
 
+const updateFlashcardSetTypoTolerance = `-- name: UpdateFlashcardSetTypoTolerance :exec
+UPDATE flashcard_sets SET typo_tolerance = $1, updated_at = LOCALTIMESTAMP(2) WHERE id = $2
+`
+
+type UpdateFlashcardSetTypoToleranceParams struct {
+	TypoTolerance int32
+	ID            int32
+}
+
+func (q *Queries) UpdateFlashcardSetTypoTolerance(ctx context.Context, arg UpdateFlashcardSetTypoToleranceParams) error {
+	_, err := q.db.Exec(ctx, updateFlashcardSetTypoTolerance, arg.TypoTolerance, arg.ID)
+	return err
+}
+
+const updateFlashcardSetVisibility = `-- name: UpdateFlashcardSetVisibility :one
+UPDATE flashcard_sets SET
+  share_token = CASE WHEN visibility = 'unlisted' AND $1::text = 'unlisted' THEN share_token ELSE $2 END,
+  visibility = $1, updated_at = LOCALTIMESTAMP(2)
+WHERE id = $3 RETURNING visibility, share_token
+`
+
+type UpdateFlashcardSetVisibilityParams struct {
+	Visibility string
+	ShareToken pgtype.Text
+	ID         int32
+}
+
+type UpdateFlashcardSetVisibilityRow struct {
+	Visibility string
+	ShareToken pgtype.Text
+}
+
+// the link token only exists while the set is unlisted, and going back to unlisted later
+// gets a new one so old links stop working
+func (q *Queries) UpdateFlashcardSetVisibility(ctx context.Context, arg UpdateFlashcardSetVisibilityParams) (UpdateFlashcardSetVisibilityRow, error) {
+	row := q.db.QueryRow(ctx, updateFlashcardSetVisibility, arg.Visibility, arg.ShareToken, arg.ID)
+	var i UpdateFlashcardSetVisibilityRow
+	err := row.Scan(&i.Visibility, &i.ShareToken)
+	return i, err
+}
+
 const verifySetMember = `-- name: VerifySetMember :one
 SELECT user_id, set_id, role, set_score, is_private from set_user WHERE set_id = $1 AND user_id = $2
 `
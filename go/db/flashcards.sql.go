@@ -10,7 +10,7 @@ import (
 )
 
 const createFlashcard = `-- name: CreateFlashcard :one
-INSERT INTO flashcards (front, back, set_id) VALUES ($1, $2, $3) RETURNING id, front, back, set_id, created_at, updated_at
+INSERT INTO flashcards (front, back, set_id) VALUES ($1, $2, $3) RETURNING id, front, back, set_id, upstream_id, created_at, updated_at
 `
 
 type CreateFlashcardParams struct {
@@ -27,6 +27,7 @@ func (q *Queries) CreateFlashcard(ctx context.Context, arg CreateFlashcardParams
 		&i.Front,
 		&i.Back,
 		&i.SetID,
+		&i.UpstreamID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -43,7 +44,7 @@ func (q *Queries) DeleteFlashcard(ctx context.Context, id int32) error {
 }
 
 const getFlashcardById = `-- name: GetFlashcardById :one
-SELECT id, front, back, set_id, created_at, updated_at FROM flashcards WHERE id = $1
+SELECT id, front, back, set_id, upstream_id, created_at, updated_at FROM flashcards WHERE id = $1
 `
 
 func (q *Queries) GetFlashcardById(ctx context.Context, id int32) (Flashcard, error) {
@@ -54,6 +55,7 @@ func (q *Queries) GetFlashcardById(ctx context.Context, id int32) (Flashcard, er
 		&i.Front,
 		&i.Back,
 		&i.SetID,
+		&i.UpstreamID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -61,7 +63,7 @@ func (q *Queries) GetFlashcardById(ctx context.Context, id int32) (Flashcard, er
 }
 
 const listFlashcardsOfASet = `-- name: ListFlashcardsOfASet :many
-SELECT id, front, back, set_id, created_at, updated_at FROM flashcards WHERE set_id = $1
+SELECT id, front, back, set_id, upstream_id, created_at, updated_at FROM flashcards WHERE set_id = $1
 `
 
 func (q *Queries) ListFlashcardsOfASet(ctx context.Context, setID int32) ([]Flashcard, error) {
@@ -78,6 +80,7 @@ func (q *Queries) ListFlashcardsOfASet(ctx context.Context, setID int32) ([]Flas
 			&i.Front,
 			&i.Back,
 			&i.SetID,
+			&i.UpstreamID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
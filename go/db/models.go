@@ -8,21 +8,78 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type Assignment struct {
+	ID                 int32
+	ClassID            int32
+	SetID              int32
+	DueAt              pgtype.Timestamp
+	RequiredMasteryPct pgtype.Int4
+	MinReviews         pgtype.Int4
+	Instructions       pgtype.Text
+	CreatedAt          pgtype.Timestamp
+	UpdatedAt          pgtype.Timestamp
+}
+
 type CardHistory struct {
 	UserID         int32
 	CardID         int32
 	Score          int32
 	TimesAttempted int32
 	IsMastered     bool
+	EaseFactor     float64
+	IntervalDays   int32
+	Repetitions    int32
+	Lapses         int32
+	CorrectStreak  int32
+	DueAt          pgtype.Timestamp
+	LastReviewedAt pgtype.Timestamp
+	LastGrade      pgtype.Text
+	LastResponseMs pgtype.Int4
+	LastAnswer     pgtype.Text
 	CreatedAt      pgtype.Timestamp
 }
 
+type ChangeLog struct {
+	ID        int64
+	Entity    string
+	Op        string
+	SetID     pgtype.Int4
+	CardID    pgtype.Int4
+	ClassID   pgtype.Int4
+	UserID    pgtype.Int4
+	TxID      int64
+	ChangedAt pgtype.Timestamp
+}
+
+type ChangeLogPruned struct {
+	ID   bool
+	TxID int64
+}
+
 type Class struct {
-	ID               int32
-	ClassName        string
-	ClassDescription string
-	CreatedAt        pgtype.Timestamp
-	UpdatedAt        pgtype.Timestamp
+	ID                    int32
+	ClassName             string
+	ClassDescription      string
+	MasteryStreak         pgtype.Int4
+	MasteryIntervalDays   pgtype.Int4
+	LeaderboardVisibility string
+	CreatedAt             pgtype.Timestamp
+	UpdatedAt             pgtype.Timestamp
+}
+
+type ClassInvite struct {
+	Token     string
+	ClassID   int32
+	ExpiresAt pgtype.Timestamp
+	CreatedAt pgtype.Timestamp
+}
+
+type ClassJoinCode struct {
+	ClassID   int32
+	Code      string
+	Enabled   bool
+	ExpiresAt pgtype.Timestamp
+	UpdatedAt pgtype.Timestamp
 }
 
 type ClassSet struct {
@@ -37,20 +94,71 @@ type ClassUser struct {
 }
 
 type Flashcard struct {
+	ID         int32
+	Front      string
+	Back       string
+	SetID      int32
+	UpstreamID pgtype.Int4
+	CreatedAt  pgtype.Timestamp
+	UpdatedAt  pgtype.Timestamp
+}
+
+type FlashcardSet struct {
+	ID                  int32
+	SetName             string
+	SetDescription      string
+	MasteryStreak       pgtype.Int4
+	MasteryIntervalDays pgtype.Int4
+	TypoTolerance       int32
+	Visibility          string
+	ShareToken          pgtype.Text
+	ForkedFrom          pgtype.Int4
+	ForkedAt            pgtype.Timestamp
+	CreatedAt           pgtype.Timestamp
+	UpdatedAt           pgtype.Timestamp
+}
+
+type Quiz struct {
 	ID        int32
-	Front     string
-	Back      string
+	UserID    int32
 	SetID     int32
+	Seed      int64
 	CreatedAt pgtype.Timestamp
-	UpdatedAt pgtype.Timestamp
 }
 
-type FlashcardSet struct {
-	ID             int32
-	SetName        string
-	SetDescription string
-	CreatedAt      pgtype.Timestamp
-	UpdatedAt      pgtype.Timestamp
+type QuizQuestion struct {
+	QuizID      int32
+	Position    int32
+	CardID      int32
+	Prompt      string
+	Choices     []string
+	AnswerIndex int32
+	ChosenIndex pgtype.Int4
+	AnsweredAt  pgtype.Timestamp
+}
+
+type ReviewLog struct {
+	ID            int32
+	UserID        int32
+	CardID        int32
+	Grade         string
+	ResponseMs    pgtype.Int4
+	Answer        pgtype.Text
+	IntervalDays  int32
+	EaseFactor    float64
+	ClientEventID pgtype.Text
+	ReviewedAt    pgtype.Timestamp
+}
+
+type Revision struct {
+	ID           int32
+	SetID        int32
+	CardID       pgtype.Int4
+	UserID       pgtype.Int4
+	Op           string
+	BeforeValues []byte
+	AfterValues  []byte
+	CreatedAt    pgtype.Timestamp
 }
 
 type SetUser struct {
@@ -61,16 +169,65 @@ type SetUser struct {
 	IsPrivate bool
 }
 
-type User struct {
+type SyncCreate struct {
+	UserID    int32
+	ClientID  string
+	CardID    pgtype.Int4
+	CreatedAt pgtype.Timestamp
+}
+
+type Test struct {
+	ID               int32
+	ClassID          int32
+	TestName         string
+	QuestionCount    int32
+	TimeLimitSeconds int32
+	MaxAttempts      int32
+	CreatedAt        pgtype.Timestamp
+	UpdatedAt        pgtype.Timestamp
+}
+
+type TestAttempt struct {
 	ID          int32
-	Username    string
-	FirstName   string
-	LastName    string
-	Email       string
-	Password    string
-	ResetToken  pgtype.Text
-	LastLogin   pgtype.Date
-	LoginStreak int32
-	CreatedAt   pgtype.Timestamp
-	UpdatedAt   pgtype.Timestamp
+	TestID      int32
+	UserID      int32
+	Attempt     int32
+	Seed        int64
+	Total       int32
+	Score       pgtype.Int4
+	StartedAt   pgtype.Timestamp
+	Deadline    pgtype.Timestamp
+	SubmittedAt pgtype.Timestamp
+}
+
+type TestAttemptQuestion struct {
+	AttemptID   int32
+	Position    int32
+	CardID      pgtype.Int4
+	Prompt      string
+	Choices     []string
+	AnswerIndex int32
+	ChosenIndex pgtype.Int4
+}
+
+type TestSet struct {
+	TestID int32
+	SetID  int32
+}
+
+type User struct {
+	ID                    int32
+	Username              string
+	FirstName             string
+	LastName              string
+	Email                 string
+	Password              string
+	ResetToken            pgtype.Text
+	LastLogin             pgtype.Date
+	LoginStreak           int32
+	LeaderboardOptIn      bool
+	LeaderboardVisibility string
+	NicknameSeed          int32
+	CreatedAt             pgtype.Timestamp
+	UpdatedAt             pgtype.Timestamp
 }
@@ -39,12 +39,13 @@ func (q *Queries) LeaveSet(ctx context.Context, arg LeaveSetParams) error {
 }
 
 const listSetsOfAUser = `-- name: ListSetsOfAUser :many
-SELECT set_id, role, set_name, set_description FROM set_user JOIN flashcard_sets ON set_user.set_id = flashcard_sets.id WHERE user_id = $1 ORDER BY set_name
+SELECT set_id, role, is_private, set_name, set_description FROM set_user JOIN flashcard_sets ON set_user.set_id = flashcard_sets.id WHERE user_id = $1 ORDER BY set_name
 `
 
 type ListSetsOfAUserRow struct {
 	SetID          int32
 	Role           string
+	IsPrivate      bool
 	SetName        string
 	SetDescription string
 }
@@ -61,6 +62,7 @@ func (q *Queries) ListSetsOfAUser(ctx context.Context, userID int32) ([]ListSets
 		if err := rows.Scan(
 			&i.SetID,
 			&i.Role,
+			&i.IsPrivate,
 			&i.SetName,
 			&i.SetDescription,
 		); err != nil {
@@ -73,3 +75,39 @@ func (q *Queries) ListSetsOfAUser(ctx context.Context, userID int32) ([]ListSets
 	}
 	return items, nil
 }
+
+const promoteSetMember = `-- name: PromoteSetMember :execrows
+UPDATE set_user SET role = 'owner' WHERE set_id = $1 AND user_id = $2
+`
+
+type PromoteSetMemberParams struct {
+	SetID  int32
+	UserID int32
+}
+
+func (q *Queries) PromoteSetMember(ctx context.Context, arg PromoteSetMemberParams) (int64, error) {
+	result, err := q.db.Exec(ctx, promoteSetMember, arg.SetID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateSetUserPrivacy = `-- name: UpdateSetUserPrivacy :execrows
+UPDATE set_user SET is_private = $1 WHERE set_id = $2 AND user_id = $3
+`
+
+type UpdateSetUserPrivacyParams struct {
+	IsPrivate bool
+	SetID     int32
+	UserID    int32
+}
+
+// a private set's score is left out of what other students see on leaderboards
+func (q *Queries) UpdateSetUserPrivacy(ctx context.Context, arg UpdateSetUserPrivacyParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateSetUserPrivacy, arg.IsPrivate, arg.SetID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
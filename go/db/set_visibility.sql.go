@@ -0,0 +1,95 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: set_visibility.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const canReadSet = `-- name: CanReadSet :one
+
+SELECT EXISTS (
+  SELECT 1 FROM flashcard_sets AS s WHERE s.id = $1 AND (
+    s.visibility = 'public'
+    OR (s.visibility = 'unlisted' AND s.share_token = $2::text)
+    OR EXISTS (
+      SELECT 1 FROM set_user WHERE set_user.set_id = s.id AND set_user.user_id = $3
+    )
+    OR (s.visibility <> 'private' AND EXISTS (
+      SELECT 1 FROM class_set JOIN class_user ON class_set.class_id = class_user.class_id
+      WHERE class_set.set_id = s.id AND class_user.user_id = $3
+    ))
+  )
+)::bool AS can_read
+`
+
+type CanReadSetParams struct {
+	SetID      int32
+	ShareToken string
+	UserID     int32
+}
+
+// a set can be read by its members, by members of classes it's been added to unless it's
+// private, by anyone with the link token while it's unlisted and by everyone once it's public
+func (q *Queries) CanReadSet(ctx context.Context, arg CanReadSetParams) (bool, error) {
+	row := q.db.QueryRow(ctx, canReadSet, arg.SetID, arg.ShareToken, arg.UserID)
+	var can_read bool
+	err := row.Scan(&can_read)
+	return can_read, err
+}
+
+const listFlashcardSets = `-- name: ListFlashcardSets :many
+SELECT s.id, s.set_name, s.set_description, s.visibility, s.created_at, s.updated_at FROM flashcard_sets AS s
+WHERE s.visibility = 'public'
+OR EXISTS (
+  SELECT 1 FROM set_user WHERE set_user.set_id = s.id AND set_user.user_id = $1
+)
+OR (s.visibility <> 'private' AND EXISTS (
+  SELECT 1 FROM class_set JOIN class_user ON class_set.class_id = class_user.class_id
+  WHERE class_set.set_id = s.id AND class_user.user_id = $1
+))
+ORDER BY s.set_name
+`
+
+type ListFlashcardSetsRow struct {
+	ID             int32
+	SetName        string
+	SetDescription string
+	Visibility     string
+	CreatedAt      pgtype.Timestamp
+	UpdatedAt      pgtype.Timestamp
+}
+
+// public sets plus every other set the user can already read. Unlisted sets only show up
+// for their members since the link is the only way in for anyone else
+func (q *Queries) ListFlashcardSets(ctx context.Context, userID int32) ([]ListFlashcardSetsRow, error) {
+	rows, err := q.db.Query(ctx, listFlashcardSets, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFlashcardSetsRow
+	for rows.Next() {
+		var i ListFlashcardSetsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.SetName,
+			&i.SetDescription,
+			&i.Visibility,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -26,7 +26,7 @@ func (q *Queries) CreateResetToken(ctx context.Context, arg CreateResetTokenPara
 }
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (username, first_name, last_name, email, password) VALUES ($1, $2, $3, $4, $5) RETURNING id, username, first_name, last_name, email, password, reset_token, last_login, login_streak, created_at, updated_at
+INSERT INTO users (username, first_name, last_name, email, password) VALUES ($1, $2, $3, $4, $5) RETURNING id, username, first_name, last_name, email, password, reset_token, last_login, login_streak, leaderboard_opt_in, leaderboard_visibility, nickname_seed, created_at, updated_at
 `
 
 type CreateUserParams struct {
@@ -56,6 +56,9 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.ResetToken,
 		&i.LastLogin,
 		&i.LoginStreak,
+		&i.LeaderboardOptIn,
+		&i.LeaderboardVisibility,
+		&i.NicknameSeed,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -72,7 +75,7 @@ func (q *Queries) DeleteUser(ctx context.Context, id int32) error {
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, username, first_name, last_name, email, password, reset_token, last_login, login_streak, created_at, updated_at FROM users WHERE email = $1
+SELECT id, username, first_name, last_name, email, password, reset_token, last_login, login_streak, leaderboard_opt_in, leaderboard_visibility, nickname_seed, created_at, updated_at FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -88,6 +91,9 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.ResetToken,
 		&i.LastLogin,
 		&i.LoginStreak,
+		&i.LeaderboardOptIn,
+		&i.LeaderboardVisibility,
+		&i.NicknameSeed,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -95,18 +101,20 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 }
 
 const getUserById = `-- name: GetUserById :one
-SELECT id, username, first_name, last_name, email, login_streak, created_at, updated_at FROM users WHERE id = $1
+SELECT id, username, first_name, last_name, email, login_streak, leaderboard_opt_in, leaderboard_visibility, created_at, updated_at FROM users WHERE id = $1
 `
 
 type GetUserByIdRow struct {
-	ID          int32
-	Username    string
-	FirstName   string
-	LastName    string
-	Email       string
-	LoginStreak int32
-	CreatedAt   pgtype.Timestamp
-	UpdatedAt   pgtype.Timestamp
+	ID                    int32
+	Username              string
+	FirstName             string
+	LastName              string
+	Email                 string
+	LoginStreak           int32
+	LeaderboardOptIn      bool
+	LeaderboardVisibility string
+	CreatedAt             pgtype.Timestamp
+	UpdatedAt             pgtype.Timestamp
 }
 
 func (q *Queries) GetUserById(ctx context.Context, id int32) (GetUserByIdRow, error) {
@@ -119,6 +127,8 @@ func (q *Queries) GetUserById(ctx context.Context, id int32) (GetUserByIdRow, er
 		&i.LastName,
 		&i.Email,
 		&i.LoginStreak,
+		&i.LeaderboardOptIn,
+		&i.LeaderboardVisibility,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -253,6 +263,34 @@ func (q *Queries) UpdateLastname(ctx context.Context, arg UpdateLastnameParams)
 	return last_name, err
 }
 
+const updateLeaderboardOptIn = `-- name: UpdateLeaderboardOptIn :exec
+UPDATE users SET leaderboard_opt_in = $1, updated_at = LOCALTIMESTAMP(2) WHERE id = $2
+`
+
+type UpdateLeaderboardOptInParams struct {
+	LeaderboardOptIn bool
+	ID               int32
+}
+
+func (q *Queries) UpdateLeaderboardOptIn(ctx context.Context, arg UpdateLeaderboardOptInParams) error {
+	_, err := q.db.Exec(ctx, updateLeaderboardOptIn, arg.LeaderboardOptIn, arg.ID)
+	return err
+}
+
+const updateLeaderboardVisibility = `-- name: UpdateLeaderboardVisibility :exec
+UPDATE users SET leaderboard_visibility = $1, updated_at = LOCALTIMESTAMP(2) WHERE id = $2
+`
+
+type UpdateLeaderboardVisibilityParams struct {
+	LeaderboardVisibility string
+	ID                    int32
+}
+
+func (q *Queries) UpdateLeaderboardVisibility(ctx context.Context, arg UpdateLeaderboardVisibilityParams) error {
+	_, err := q.db.Exec(ctx, updateLeaderboardVisibility, arg.LeaderboardVisibility, arg.ID)
+	return err
+}
+
 const updatePassword = `-- name: UpdatePassword :exec
 UPDATE users SET password = $1, updated_at = LOCALTIMESTAMP(2) WHERE id = $2
 `
@@ -0,0 +1,172 @@
+package grading
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Grading of typed answers. Both sides are normalized first so "  Él está, bien!" and
+// "el esta bien" compare equal; whatever is left over is measured in edit distance.
+
+type Op string
+
+const (
+	// MaxLength is the longest text Check measures, in runes. Longer text is only checked
+	// for an exact match
+	MaxLength int = 5000
+
+	// the diff needs the whole edit matrix, so it's left out past this many cells
+	maxDiffCells int = 1 << 20
+)
+
+const (
+	Equal   Op = "equal"
+	Missing Op = "missing" // in the expected answer but not typed
+	Extra   Op = "extra"   // typed but not in the expected answer
+)
+
+type Chunk struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+type Result struct {
+	Correct  bool    `json:"correct"`
+	Exact    bool    `json:"exact"`
+	Distance int     `json:"distance"`
+	Diff     []Chunk `json:"diff"`
+}
+
+// Normalize lowercases s, strips accents and punctuation and collapses whitespace
+func Normalize(s string) string {
+	// transformers keep state, so build the chain per call
+	stripAccents := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	s, _, err := transform.String(stripAccents, s)
+	if err != nil {
+		return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	}
+
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, s)
+
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Check grades given against expected. Up to tolerance typos are accepted, but never
+// more than one per four characters of the answer, so short words must be exact.
+// Past MaxLength only an exact match is correct and distance is the longer length
+func Check(expected, given string, tolerance int) Result {
+	want := []rune(Normalize(expected))
+	got := []rune(Normalize(given))
+
+	if len(want) > MaxLength || len(got) > MaxLength {
+		exact := slices.Equal(want, got)
+		res := Result{Correct: exact, Exact: exact}
+		if !exact {
+			res.Distance = max(len(want), len(got))
+		}
+		return res
+	}
+
+	distance := editDistance(want, got)
+	allowed := min(tolerance, len(want)/4)
+
+	res := Result{
+		Correct:  distance <= allowed,
+		Exact:    distance == 0,
+		Distance: distance,
+	}
+	if (len(want)+1)*(len(got)+1) <= maxDiffCells {
+		res.Diff = compare(want, got)
+	}
+	return res
+}
+
+// editDistance is the Levenshtein distance between want and got, keeping only two rows
+// of the matrix
+func editDistance(want, got []rune) int {
+	prev := make([]int, len(got)+1)
+	cur := make([]int, len(got)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(want); i++ {
+		cur[0] = i
+		for j := 1; j <= len(got); j++ {
+			cost := 1
+			if want[i-1] == got[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(got)]
+}
+
+// compare returns one cheapest way of turning got into want. It keeps the whole
+// matrix, so callers bound the input first
+func compare(want, got []rune) []Chunk {
+	// d[i][j] is the distance between want[:i] and got[:j]
+	d := make([][]int, len(want)+1)
+	for i := range d {
+		d[i] = make([]int, len(got)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(want); i++ {
+		for j := 1; j <= len(got); j++ {
+			cost := 1
+			if want[i-1] == got[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+		}
+	}
+
+	// walk back from the end, building the diff in reverse
+	var ops []Op
+	var text []rune
+	i, j := len(want), len(got)
+	for i > 0 || j > 0 {
+		switch {
+		case i > 0 && j > 0 && want[i-1] == got[j-1] && d[i][j] == d[i-1][j-1]:
+			ops, text = append(ops, Equal), append(text, want[i-1])
+			i, j = i-1, j-1
+		case i > 0 && j > 0 && d[i][j] == d[i-1][j-1]+1:
+			// a substitution reads as "typed this, should be that"
+			ops, text = append(ops, Missing, Extra), append(text, want[i-1], got[j-1])
+			i, j = i-1, j-1
+		case i > 0 && d[i][j] == d[i-1][j]+1:
+			ops, text = append(ops, Missing), append(text, want[i-1])
+			i--
+		default:
+			ops, text = append(ops, Extra), append(text, got[j-1])
+			j--
+		}
+	}
+
+	var diff []Chunk
+	for k := len(ops) - 1; k >= 0; k-- {
+		if n := len(diff); n > 0 && diff[n-1].Op == ops[k] {
+			diff[n-1].Text += string(text[k])
+			continue
+		}
+		diff = append(diff, Chunk{Op: ops[k], Text: string(text[k])})
+	}
+
+	return diff
+}
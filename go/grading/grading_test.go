@@ -0,0 +1,108 @@
+package grading
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"  Él está, bien!", "el esta bien"},
+		{"el esta bien", "el esta bien"},
+		{"¿Qué\tPASA?\n", "que pasa"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		expected  string
+		given     string
+		tolerance int
+		correct   bool
+		exact     bool
+		distance  int
+	}{
+		{"exact", "la biblioteca", "la biblioteca", 2, true, true, 0},
+		{"exact after normalizing", "¡Está bien!", "esta bien", 2, true, true, 0},
+		{"typo within tolerance", "la biblioteca", "la bibloteca", 2, true, false, 1},
+		{"typos over tolerance", "la biblioteca", "la bivlotecq", 2, false, false, 3},
+		{"short words must be exact", "sol", "sal", 2, false, false, 1},
+		{"zero tolerance", "la biblioteca", "la bibloteca", 0, false, false, 1},
+		{"nothing typed", "perro", "", 2, false, false, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Check(tt.expected, tt.given, tt.tolerance)
+
+			if got.Correct != tt.correct || got.Exact != tt.exact || got.Distance != tt.distance {
+				t.Errorf("got correct %v exact %v distance %d, want %v %v %d",
+					got.Correct, got.Exact, got.Distance, tt.correct, tt.exact, tt.distance)
+			}
+		})
+	}
+}
+
+func TestCheckDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		given    string
+		want     []Chunk
+	}{
+		{"equal", "hola", "hola", []Chunk{{Equal, "hola"}}},
+		{"substitution reads typed then expected", "gato", "gaso", []Chunk{
+			{Equal, "ga"}, {Extra, "s"}, {Missing, "t"}, {Equal, "o"},
+		}},
+		{"missing letter", "casa", "csa", []Chunk{{Equal, "c"}, {Missing, "a"}, {Equal, "sa"}}},
+		{"extra letter", "casa", "caxsa", []Chunk{{Equal, "ca"}, {Extra, "x"}, {Equal, "sa"}}},
+		{"nothing typed", "sol", "", []Chunk{{Missing, "sol"}}},
+		{"nothing expected", "", "sol", []Chunk{{Extra, "sol"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Check(tt.expected, tt.given, 0).Diff
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckLongText(t *testing.T) {
+	long := strings.Repeat("palabra ", MaxLength/8+1)
+
+	t.Run("over MaxLength only an exact match is correct", func(t *testing.T) {
+		got := Check(long, long, 2)
+		if !got.Correct || !got.Exact || got.Distance != 0 || got.Diff != nil {
+			t.Errorf("got %+v, want an exact match without a diff", got)
+		}
+
+		got = Check(long+"x", long, 2)
+		if got.Correct || got.Exact || got.Distance != len([]rune(Normalize(long+"x"))) || got.Diff != nil {
+			t.Errorf("got correct %v exact %v distance %d, want incorrect at the longer length", got.Correct, got.Exact, got.Distance)
+		}
+	})
+
+	t.Run("large inputs get a distance but no diff", func(t *testing.T) {
+		want := strings.Repeat("a", MaxLength)
+		got := Check(want, strings.Repeat("a", 300), 2)
+		if got.Distance != MaxLength-300 || got.Diff != nil {
+			t.Errorf("got distance %d and %d diff chunks, want %d and none", got.Distance, len(got.Diff), MaxLength-300)
+		}
+	})
+}
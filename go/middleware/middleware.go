@@ -85,7 +85,7 @@ func (h *Handler) VerifyClassMemberMW(next http.Handler) http.Handler {
 			classID int32
 		)
 
-		if strings.HasSuffix(route, "/class_set/") || strings.HasSuffix(route, "/class_user/") {
+		if strings.HasSuffix(route, "/class_set/") || strings.HasSuffix(route, "/class_user/") || strings.HasSuffix(route, "/class_user/members") {
 			headerVals, err := GetHeaderVals(r, class_id)
 			if err != nil {
 				LogAndSendError(w, err, "Header error", http.StatusBadRequest)
@@ -179,6 +179,20 @@ func (h *Handler) VerifySetMemberMW(next http.Handler) http.Handler {
 				LogAndSendError(w, err, "Invalid set or fc id", http.StatusBadRequest)
 				return
 			}
+
+			// card routes send the card's id, membership is checked against its set
+			if strings.Contains(r.URL.Path, "/flashcards/") && !strings.Contains(r.URL.Path, "/flashcards/sets") {
+				card, err := query.GetFlashcardById(ctx, setID)
+				if err != nil {
+					if strings.Contains(err.Error(), "no rows in result set") {
+						LogAndSendError(w, err, "Flashcard not found", http.StatusNotFound)
+						return
+					}
+					LogAndSendError(w, err, "Database error", http.StatusInternalServerError)
+					return
+				}
+				setID = card.SetID
+			}
 		}
 
 		member, err := query.VerifySetMember(ctx, db.VerifySetMemberParams{
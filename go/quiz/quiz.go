@@ -0,0 +1,78 @@
+package quiz
+
+import (
+	"cmp"
+	"errors"
+	"math/rand/v2"
+	"slices"
+)
+
+// Multiple choice questions built from a set: the prompt is a card's front and the
+// wrong choices are backs of other cards in the same set.
+
+const MaxChoices int = 4
+
+var ErrTooFewCards error = errors.New("a quiz needs at least two cards with different backs")
+
+type Card struct {
+	ID    int32
+	Front string
+	Back  string
+}
+
+type Question struct {
+	CardID  int32
+	Prompt  string
+	Choices []string
+	Answer  int32 // index into Choices
+}
+
+// Generate draws count questions (capped at the number of cards) from cards.
+// The same cards, count and seed always give the same quiz
+func Generate(cards []Card, count int, seed int64) ([]Question, error) {
+	cards = slices.Clone(cards)
+	slices.SortFunc(cards, func(a, b Card) int { return cmp.Compare(a.ID, b.ID) })
+
+	var backs []string
+	for _, c := range cards {
+		if !slices.Contains(backs, c.Back) {
+			backs = append(backs, c.Back)
+		}
+	}
+	if len(backs) < 2 {
+		return nil, ErrTooFewCards
+	}
+
+	rng := rand.New(rand.NewPCG(uint64(seed), uint64(seed)>>32))
+
+	count = min(count, len(cards))
+	picked := rng.Perm(len(cards))[:count]
+	choiceCount := min(MaxChoices, len(backs))
+
+	questions := make([]Question, 0, count)
+	for _, i := range picked {
+		card := cards[i]
+
+		var distractors []string
+		for _, j := range rng.Perm(len(backs)) {
+			if len(distractors) == choiceCount-1 {
+				break
+			}
+			if backs[j] != card.Back {
+				distractors = append(distractors, backs[j])
+			}
+		}
+
+		answer := rng.IntN(choiceCount)
+		choices := slices.Insert(distractors, answer, card.Back)
+
+		questions = append(questions, Question{
+			CardID:  card.ID,
+			Prompt:  card.Front,
+			Choices: choices,
+			Answer:  int32(answer),
+		})
+	}
+
+	return questions, nil
+}
@@ -0,0 +1,106 @@
+package quiz
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	cards := []Card{
+		{ID: 1, Front: "uno", Back: "one"},
+		{ID: 2, Front: "dos", Back: "two"},
+		{ID: 3, Front: "tres", Back: "three"},
+		{ID: 4, Front: "cuatro", Back: "four"},
+		{ID: 5, Front: "cinco", Back: "five"},
+		{ID: 6, Front: "seis", Back: "six"},
+	}
+
+	t.Run("each question has the card's back at the answer index", func(t *testing.T) {
+		questions, err := Generate(cards, 6, 42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(questions) != 6 {
+			t.Fatalf("got %d questions, want 6", len(questions))
+		}
+
+		seen := make(map[int32]bool)
+		for _, q := range questions {
+			card := cards[slices.IndexFunc(cards, func(c Card) bool { return c.ID == q.CardID })]
+			if q.Prompt != card.Front {
+				t.Errorf("card %d: got prompt %q, want %q", q.CardID, q.Prompt, card.Front)
+			}
+			if len(q.Choices) != MaxChoices {
+				t.Errorf("card %d: got %d choices, want %d", q.CardID, len(q.Choices), MaxChoices)
+			}
+			if q.Choices[q.Answer] != card.Back {
+				t.Errorf("card %d: choice %d is %q, want %q", q.CardID, q.Answer, q.Choices[q.Answer], card.Back)
+			}
+			for i, c := range q.Choices {
+				if slices.Index(q.Choices, c) != i {
+					t.Errorf("card %d: choice %q appears twice", q.CardID, c)
+				}
+			}
+			if seen[q.CardID] {
+				t.Errorf("card %d asked twice", q.CardID)
+			}
+			seen[q.CardID] = true
+		}
+	})
+
+	t.Run("same seed gives the same quiz in any card order", func(t *testing.T) {
+		a, err := Generate(cards, 4, 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reversed := slices.Clone(cards)
+		slices.Reverse(reversed)
+		b, err := Generate(reversed, 4, 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(a, b) {
+			t.Errorf("got %+v, want %+v", b, a)
+		}
+	})
+
+	t.Run("count is capped at the number of cards", func(t *testing.T) {
+		questions, err := Generate(cards[:3], 10, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(questions) != 3 {
+			t.Errorf("got %d questions, want 3", len(questions))
+		}
+	})
+
+	t.Run("choices are capped at the distinct backs", func(t *testing.T) {
+		questions, err := Generate([]Card{
+			{ID: 1, Front: "a", Back: "x"},
+			{ID: 2, Front: "b", Back: "y"},
+			{ID: 3, Front: "c", Back: "y"},
+		}, 3, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, q := range questions {
+			if len(q.Choices) != 2 {
+				t.Errorf("card %d: got choices %v, want 2", q.CardID, q.Choices)
+			}
+		}
+	})
+
+	t.Run("too few distinct backs", func(t *testing.T) {
+		_, err := Generate([]Card{
+			{ID: 1, Front: "a", Back: "same"},
+			{ID: 2, Front: "b", Back: "same"},
+		}, 2, 1)
+		if !errors.Is(err, ErrTooFewCards) {
+			t.Errorf("got %v, want %v", err, ErrTooFewCards)
+		}
+	})
+}
@@ -10,13 +10,32 @@ func Protected(r *chi.Mux, h *controllers.DBHandler) {
 
 	// -------------------complex-------------------------
 
+	r.Route("/assignments", func(r chi.Router) {
+		r.Route("/", func(r chi.Router) {
+			r.Use(h.VerifyClassMemberMW) // teachers only
+			r.Get("/", h.ListAssignmentsInAClass)
+			r.Post("/", h.CreateAssignment)
+			r.Put("/", h.UpdateAssignment)
+		})
+		r.Get("/mine", h.ListAssignmentsOfAUser)
+	})
+
 	r.Route("/card_history", func(r chi.Router) {
 		// these are upserts, one each for (in)correct
 		r.Post("/correct", h.UpdateFlashcardScore)
 		r.Post("/incorrect", h.UpdateFlashcardScore)
+		// graded (again/hard/good/easy) upsert, json body
+		r.Post("/review", h.ReviewFlashcard)
+		// typed-answer mode, graded server side
+		r.Post("/typed", h.ReviewTypedAnswer)
+		// offline reviews from the mobile app, idempotent on client_id
+		r.Post("/batch", h.ReviewFlashcardBatch)
 
 		r.Get("/", h.GetCardScore)
 		r.Get("/set", h.GetScoresInASet)
+		r.Get("/due", h.ListDueCards)
+		r.Get("/mastery", h.ListMasteryCounts)
+		r.Get("/mastery/set", h.GetMasteryCountsInASet)
 	})
 
 	r.Route("/class_set", func(r chi.Router) {
@@ -33,18 +52,59 @@ func Protected(r *chi.Mux, h *controllers.DBHandler) {
 		r.Route("/", func(r chi.Router) {
 			r.Use(h.VerifyClassMemberMW)
 			r.Delete("/", h.LeaveClass)
+			r.Get("/members", h.ListMembersOfAClass)
 		})
 
 		r.Post("/", h.JoinClass)
 		r.Get("/classes", h.ListClassesOfAUser)
-		r.Get("/members", h.ListMembersOfAClass)
-		// r.Get("/getstudents", h.ListStudentsOfAClass)
-		// r.Get("/getteacher", h.ListTeachersOfAClass)
+	})
+
+	// opted in users only, see /users/leaderboard_opt_in
+	r.Route("/leaderboard", func(r chi.Router) {
+		r.Get("/", h.ListGlobalLeaderboard)
+	})
+
+	// multiple choice quizzes generated and graded server side
+	r.Route("/quizzes", func(r chi.Router) {
+		r.Get("/", h.GetQuiz)
+		r.Post("/", h.CreateQuiz)
+		r.Post("/answer", h.AnswerQuiz)
+	})
+
+	r.Route("/review_log", func(r chi.Router) {
+		r.Get("/card", h.ListReviewsOfACard)
+		r.Get("/set", h.ListReviewsInASet)
+		r.Get("/daily", h.ListDailyReviews)
+
+		r.Route("/class", func(r chi.Router) {
+			r.Use(h.VerifyClassMemberMW)
+			r.Get("/daily", h.ListDailyReviewsInAClass)
+		})
+	})
+
+	// offline sync for the mobile app
+	r.Route("/sync", func(r chi.Router) {
+		r.Get("/", h.PullChanges)
+		r.Post("/", h.PushChanges)
+	})
+
+	// timed tests; creating and listing go through the class (id header)
+	r.Route("/tests", func(r chi.Router) {
+		r.Route("/", func(r chi.Router) {
+			r.Use(h.VerifyClassMemberMW)
+			r.Post("/", h.CreateTest)
+			r.Get("/list", h.ListTestsInAClass)
+		})
+		r.Get("/attempts", h.ListTestAttempts)
+		r.Get("/attempt", h.GetTestAttempt)
+		r.Post("/start", h.StartTestAttempt)
+		r.Post("/submit", h.SubmitTestAttempt)
 	})
 
 	r.Route("/set_user", func(r chi.Router) {
 		r.Route("/", func(r chi.Router) {
 			r.Use(h.VerifySetMemberMW)
+			r.Put("/private", h.UpdateSetUserPrivacy)
 			r.Delete("/", h.LeaveSet) //never called
 		})
 		r.Post("/", h.JoinSet)
@@ -61,12 +121,36 @@ func Protected(r *chi.Mux, h *controllers.DBHandler) {
 			r.Get("/", h.GetClassById)
 			r.Put("/class_name", h.UpdateClass)
 			r.Put("/class_description", h.UpdateClass)
+			r.Put("/mastery", h.UpdateClassMasteryRule)
+			r.Put("/leaderboard_visibility", h.UpdateClassLeaderboardVisibility)
 			r.Delete("/", h.DeleteClass) //never called
+
+			// roster management, teachers only
+			r.Get("/students", h.ListStudentsOfAClass)
+			r.Get("/teachers", h.ListTeachersOfAClass)
+			r.Put("/role", h.UpdateClassMemberRole)
+			r.Put("/teacher", h.GrantClassTeacher) // same as /role with role: teacher
+			r.Put("/transfer", h.TransferClass)
+			r.Post("/roster", h.ImportRoster)
+
+			// teachers see the whole class, students only themselves
+			r.Get("/progress", h.GetClassProgressReport)
+			r.Get("/hardest_cards", h.ListHardestCardsInAClass)
+			r.Get("/gradebook", h.GetClassGradebook)
+
+			// teachers only; students join with these through /class_user
+			r.Get("/join_code", h.GetJoinCode)
+			r.Post("/join_code", h.RegenerateJoinCode)
+			r.Put("/join_code", h.UpdateJoinCode)
+			r.Get("/invites", h.ListClassInvites)
+			r.Post("/invites", h.CreateClassInvite)
+			r.Delete("/invites", h.DeleteClassInvite)
 		})
 
 		r.Route("/leaderboard", func(r chi.Router) {
 			r.Use(h.VerifyClassMemberMW)
 			r.Get("/", h.GetClassLeaderboard)
+			r.Get("/window", h.ListClassLeaderboard) // weekly, monthly or custom range
 		})
 
 		r.Get("/list", h.ListClasses)
@@ -77,6 +161,7 @@ func Protected(r *chi.Mux, h *controllers.DBHandler) {
 		r.Route("/", func(r chi.Router) {
 			r.Use(h.VerifySetMemberMW) // Ensure only the owner can update/delete
 			r.Post("/", h.CreateFlashcard)
+			r.Post("/import", h.ImportFlashcards) // csv, tsv or pasted text
 			r.Put("/front", h.UpdateFlashcard)
 			r.Put("/back", h.UpdateFlashcard)
 			// r.Put("/set_id", h.UpdateFlashcard)
@@ -91,10 +176,23 @@ func Protected(r *chi.Mux, h *controllers.DBHandler) {
 				r.Get("/", h.GetFlashcardSetById)
 				r.Put("/set_name", h.UpdateFlashcardSet)
 				r.Put("/set_description", h.UpdateFlashcardSet)
+				r.Put("/mastery", h.UpdateFlashcardSetMasteryRule)
+				r.Put("/typo_tolerance", h.UpdateFlashcardSetTypoTolerance)
+				r.Put("/visibility", h.UpdateFlashcardSetVisibility)
+				r.Get("/anki", h.ExportAnki)
+				r.Get("/export", h.ExportFlashcardSet) // text, csv or json bundle
+				r.Post("/fork", h.ForkFlashcardSet)
+				r.Get("/fork/diff", h.DiffForkWithUpstream)
+				r.Get("/revisions", h.ListSetRevisions)
+				r.Get("/revisions/diff", h.DiffSetRevisions)
+				r.Post("/revisions/rollback", h.RollbackSet)
+				r.Put("/owner", h.GrantSetOwner)
 				r.Delete("/", h.DeleteFlashcardSet)
 			})
 			r.Get("/list", h.ListFlashcardSets)
 			r.Post("/", h.CreateFlashcardSet)
+			r.Post("/anki", h.ImportAnki)
+			r.Post("/bundle", h.ImportSetBundle)
 		})
 	})
 
@@ -108,6 +206,8 @@ func Protected(r *chi.Mux, h *controllers.DBHandler) {
 		r.Put("/first_name", h.UpdateUser)
 		r.Put("/last_name", h.UpdateUser)
 		r.Put("/password", h.UpdateUser)
+		r.Put("/leaderboard_opt_in", h.UpdateLeaderboardOptIn)
+		r.Put("/leaderboard_visibility", h.UpdateLeaderboardVisibility)
 		r.Delete("/", h.DeleteUser)
 	})
 }
@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// SM-2 style spaced repetition, loosely following Anki's variant:
+// a failed card drops back to relearning, passing grades grow the interval
+// by the card's ease factor, and the ease factor drifts with hard/easy answers.
+
+type Grade int32
+
+const (
+	Again Grade = iota + 1
+	Hard
+	Good
+	Easy
+)
+
+const (
+	DefaultEase  float64       = 2.5
+	minEase      float64       = 1.3
+	hardFactor   float64       = 1.2
+	easyBonus    float64       = 1.3
+	relearnDelay time.Duration = 10 * time.Minute
+	day          time.Duration = 24 * time.Hour
+)
+
+var ErrGrade error = errors.New("grade must be one of again, hard, good, easy")
+
+var gradeNames = map[Grade]string{
+	Again: "again",
+	Hard:  "hard",
+	Good:  "good",
+	Easy:  "easy",
+}
+
+// State is the per-user/per-card schedule stored in card_history
+type State struct {
+	EaseFactor    float64
+	IntervalDays  int32
+	Repetitions   int32
+	Lapses        int32
+	CorrectStreak int32
+}
+
+// MasteryRule decides when a card counts as mastered. A zero field disables that
+// criterion; a card meeting either enabled criterion is mastered
+type MasteryRule struct {
+	Streak       int32 // consecutive passing answers
+	IntervalDays int32 // scheduled interval
+}
+
+// DefaultMastery applies when neither the set nor any of the user's classes override it
+var DefaultMastery = MasteryRule{Streak: 3, IntervalDays: 21}
+
+// New returns the schedule of a card that has never been reviewed
+func New() State {
+	return State{EaseFactor: DefaultEase}
+}
+
+// Valid reports whether g is one of the four review grades
+func (g Grade) Valid() bool {
+	return g >= Again && g <= Easy
+}
+
+// ParseGrade maps the names stored in card_history.last_grade back to a Grade
+func ParseGrade(name string) (Grade, error) {
+	for g, n := range gradeNames {
+		if n == name {
+			return g, nil
+		}
+	}
+	return 0, ErrGrade
+}
+
+func (g Grade) String() string {
+	return gradeNames[g]
+}
+
+// Passed reports whether g counts as a correct answer
+func (g Grade) Passed() bool {
+	return g > Again
+}
+
+// Next applies a review graded g to s and returns the new state along with
+// how long from now the card should next be shown
+func Next(s State, g Grade) (State, time.Duration) {
+	if s.EaseFactor == 0 {
+		s.EaseFactor = DefaultEase
+	}
+
+	switch g {
+	case Again:
+		s.Repetitions = 0
+		s.Lapses++
+		s.CorrectStreak = 0
+		s.IntervalDays = 0
+		s.EaseFactor = math.Max(minEase, s.EaseFactor-0.2)
+		return s, relearnDelay
+	case Hard:
+		s.EaseFactor = math.Max(minEase, s.EaseFactor-0.15)
+		if s.Repetitions == 0 {
+			s.IntervalDays = 1
+		} else {
+			s.IntervalDays = grow(s.IntervalDays, hardFactor)
+		}
+	case Good:
+		switch s.Repetitions {
+		case 0:
+			s.IntervalDays = 1
+		case 1:
+			s.IntervalDays = 6
+		default:
+			s.IntervalDays = grow(s.IntervalDays, s.EaseFactor)
+		}
+	case Easy:
+		if s.Repetitions == 0 {
+			s.IntervalDays = 4
+		} else {
+			s.IntervalDays = grow(s.IntervalDays, s.EaseFactor*easyBonus)
+		}
+		s.EaseFactor += 0.15
+	}
+
+	s.Repetitions++
+	s.CorrectStreak++
+	return s, time.Duration(s.IntervalDays) * day
+}
+
+// Mastered reports whether s satisfies the rule
+func (m MasteryRule) Mastered(s State) bool {
+	return (m.Streak > 0 && s.CorrectStreak >= m.Streak) ||
+		(m.IntervalDays > 0 && s.IntervalDays >= m.IntervalDays)
+}
+
+// grow multiplies an interval, always moving it forward by at least a day
+func grow(interval int32, factor float64) int32 {
+	next := int32(math.Round(float64(interval) * factor))
+	if next <= interval {
+		next = interval + 1
+	}
+	return next
+}
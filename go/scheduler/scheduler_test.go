@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	t.Run("good answers follow 1, 6, then ease growth", func(t *testing.T) {
+		s := New()
+		var got []int32
+		for range 3 {
+			s, _ = Next(s, Good)
+			got = append(got, s.IntervalDays)
+		}
+		want := []int32{1, 6, 15}
+
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("got %v, want %v", got, want)
+				break
+			}
+		}
+	})
+
+	t.Run("again resets repetitions and counts a lapse", func(t *testing.T) {
+		s := State{EaseFactor: 2.5, IntervalDays: 15, Repetitions: 3}
+		s, due := Next(s, Again)
+
+		if s.Repetitions != 0 || s.Lapses != 1 || s.IntervalDays != 0 {
+			t.Errorf("got %+v, want reset state with one lapse", s)
+		}
+		if due != relearnDelay {
+			t.Errorf("got due %v, want %v", due, relearnDelay)
+		}
+		if s.EaseFactor != 2.3 {
+			t.Errorf("got ease %v, want 2.3", s.EaseFactor)
+		}
+	})
+
+	t.Run("ease never drops below the floor", func(t *testing.T) {
+		s := New()
+		for range 20 {
+			s, _ = Next(s, Again)
+		}
+
+		if s.EaseFactor != minEase {
+			t.Errorf("got ease %v, want %v", s.EaseFactor, minEase)
+		}
+	})
+
+	t.Run("due duration matches the interval", func(t *testing.T) {
+		s, due := Next(New(), Easy)
+
+		if due != time.Duration(s.IntervalDays)*day {
+			t.Errorf("got due %v for interval %d", due, s.IntervalDays)
+		}
+	})
+}
+
+func TestMastered(t *testing.T) {
+	t.Run("either enabled criterion masters a card", func(t *testing.T) {
+		rule := MasteryRule{Streak: 3, IntervalDays: 21}
+
+		if !rule.Mastered(State{CorrectStreak: 3}) {
+			t.Errorf("streak of 3 should be mastered")
+		}
+		if !rule.Mastered(State{IntervalDays: 30}) {
+			t.Errorf("interval of 30 should be mastered")
+		}
+		if rule.Mastered(State{CorrectStreak: 2, IntervalDays: 6}) {
+			t.Errorf("streak of 2 and interval of 6 should not be mastered")
+		}
+	})
+
+	t.Run("zero disables a criterion", func(t *testing.T) {
+		rule := MasteryRule{Streak: 0, IntervalDays: 10}
+
+		if rule.Mastered(State{CorrectStreak: 50}) {
+			t.Errorf("streak should be ignored when disabled")
+		}
+	})
+}
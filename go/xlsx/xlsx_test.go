@@ -0,0 +1,161 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+// the parts of sheet1.xml the tests look at
+type sheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R      string `xml:"r,attr"`
+			T      string `xml:"t,attr"`
+			S      string `xml:"s,attr"`
+			V      string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readSheet(t *testing.T, workbook []byte) sheet {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(workbook), int64(len(workbook)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := zr.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var s sheet
+	if err = xml.Unmarshal(data, &s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestWrite(t *testing.T) {
+	header := make([]any, 28)
+	for i := range header {
+		header[i] = columnName(i)
+	}
+	rows := [][]any{
+		header,
+		{"Tom & <Jerry>", 42, int32(-7), int64(1) << 40, 0.75, true, nil, false},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "Period 1", rows); err != nil {
+		t.Fatal(err)
+	}
+	s := readSheet(t, buf.Bytes())
+
+	if len(s.Rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(s.Rows))
+	}
+
+	t.Run("header is bold and refs go past Z", func(t *testing.T) {
+		cells := s.Rows[0].Cells
+		if len(cells) != 28 {
+			t.Fatalf("got %d header cells, want 28", len(cells))
+		}
+		for _, i := range []int{0, 25, 26, 27} {
+			want := columnName(i) + "1"
+			if cells[i].R != want || cells[i].Inline != columnName(i) || cells[i].S != "1" {
+				t.Errorf("cell %d: got ref %q text %q style %q, want %q bold", i, cells[i].R, cells[i].Inline, cells[i].S, want)
+			}
+		}
+		if cells[27].R != "AB1" {
+			t.Errorf("got %q, want AB1", cells[27].R)
+		}
+	})
+
+	t.Run("cells keep their types and text is escaped", func(t *testing.T) {
+		tests := []struct {
+			ref    string
+			typ    string
+			value  string
+			inline string
+		}{
+			{"A2", "inlineStr", "", "Tom & <Jerry>"},
+			{"B2", "", "42", ""},
+			{"C2", "", "-7", ""},
+			{"D2", "", "1099511627776", ""},
+			{"E2", "", "0.75", ""},
+			{"F2", "b", "1", ""},
+			{"H2", "b", "0", ""},
+		}
+
+		cells := s.Rows[1].Cells
+		if len(cells) != len(tests) {
+			t.Fatalf("got %d cells, want %d with the nil cell skipped", len(cells), len(tests))
+		}
+		for i, tt := range tests {
+			c := cells[i]
+			if c.R != tt.ref || c.T != tt.typ || c.V != tt.value || c.Inline != tt.inline || c.S != "" {
+				t.Errorf("got %+v, want %+v", c, tt)
+			}
+		}
+	})
+
+	t.Run("unsupported cell type", func(t *testing.T) {
+		err := Write(io.Discard, "x", [][]any{{struct{}{}}})
+		if err == nil || !strings.Contains(err.Error(), "unsupported") {
+			t.Errorf("got %v, want an unsupported cell type error", err)
+		}
+	})
+}
+
+func TestColumnName(t *testing.T) {
+	tests := []struct {
+		in   int
+		want string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+
+	for _, tt := range tests {
+		if got := columnName(tt.in); got != tt.want {
+			t.Errorf("columnName(%d) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCleanSheetName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Period 1", "Period 1"},
+		{"  a/b:c?  ", "a_b_c_"},
+		{"", "Sheet1"},
+		{strings.Repeat("é", 40), strings.Repeat("é", maxSheetName)},
+	}
+
+	for _, tt := range tests {
+		if got := cleanSheetName(tt.in); got != tt.want {
+			t.Errorf("cleanSheetName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+-- name: CreateAssignment :one
+INSERT INTO assignments (class_id, set_id, due_at, required_mastery_pct, min_reviews, instructions)
+VALUES (sqlc.arg(class_id), sqlc.arg(set_id), sqlc.arg(due_at)::timestamptz::timestamp, sqlc.narg(required_mastery_pct), sqlc.narg(min_reviews), sqlc.narg(instructions))
+RETURNING *;
+
+-- name: UpdateAssignment :one
+UPDATE assignments SET due_at = sqlc.arg(due_at)::timestamptz::timestamp, required_mastery_pct = sqlc.narg(required_mastery_pct),
+min_reviews = sqlc.narg(min_reviews), instructions = sqlc.narg(instructions), updated_at = LOCALTIMESTAMP(2)
+WHERE id = sqlc.arg(id) AND class_id = sqlc.arg(class_id)
+RETURNING *;
+
+-- name: ListAssignmentsInAClass :many
+SELECT a.*, s.set_name FROM assignments AS a
+JOIN flashcard_sets AS s ON s.id = a.set_id
+WHERE a.class_id = $1
+ORDER BY a.due_at, a.id;
+
+-- progress towards every assignment of the classes the user is a student in
+-- name: ListAssignmentsOfAUser :many
+SELECT a.*, c.class_name, s.set_name,
+(a.due_at < LOCALTIMESTAMP(2))::bool AS past_due,
+COUNT(f.id)::int AS total_cards,
+(COUNT(f.id) FILTER (WHERE ch.is_mastered))::int AS mastered_cards,
+COALESCE(SUM(ch.times_attempted), 0)::int AS reviews
+FROM assignments AS a
+JOIN class_user AS cu ON cu.class_id = a.class_id AND cu.user_id = $1 AND cu.role = 'student'
+JOIN classes AS c ON c.id = a.class_id
+JOIN flashcard_sets AS s ON s.id = a.set_id
+LEFT JOIN flashcards AS f ON f.set_id = a.set_id
+LEFT JOIN card_history AS ch ON ch.card_id = f.id AND ch.user_id = $1
+GROUP BY a.id, c.class_name, s.set_name
+ORDER BY a.due_at, a.id;
@@ -25,4 +25,78 @@ SELECT COUNT(card_id) FROM card_history WHERE user_id = $1;
 SELECT COUNT(is_mastered) FROM card_history WHERE user_id = $1 AND is_mastered = TRUE;
 
 -- name: GetTotalCardViews :one
-SELECT COALESCE(SUM(times_attempted), 0) FROM card_history WHERE user_id = $1;
\ No newline at end of file
+SELECT COALESCE(SUM(times_attempted), 0) FROM card_history WHERE user_id = $1;
+
+-- name: GetCardSchedule :one
+SELECT ease_factor, interval_days, repetitions, lapses, correct_streak, due_at,
+COALESCE(sqlc.narg(reviewed_at)::timestamptz::timestamp < last_reviewed_at, false)::bool AS stale
+FROM card_history
+WHERE user_id = sqlc.arg(user_id) AND card_id = sqlc.arg(card_id);
+
+-- name: ListCardSchedulesInASet :many
+SELECT card_id, ease_factor, interval_days, repetitions, lapses, due_at FROM card_history
+JOIN flashcards ON card_history.card_id = flashcards.id
+WHERE user_id = $1 AND set_id = $2;
+
+-- name: UpdateCardSchedule :exec
+UPDATE card_history SET ease_factor = sqlc.arg(ease_factor), interval_days = sqlc.arg(interval_days), repetitions = sqlc.arg(repetitions),
+lapses = sqlc.arg(lapses), correct_streak = sqlc.arg(correct_streak), is_mastered = sqlc.arg(is_mastered),
+due_at = COALESCE(sqlc.narg(reviewed_at)::timestamptz::timestamp, LOCALTIMESTAMP(2)) + (sqlc.arg(due_in_minutes)::int * INTERVAL '1 minute'),
+last_reviewed_at = COALESCE(sqlc.narg(reviewed_at)::timestamptz::timestamp, LOCALTIMESTAMP(2)),
+last_grade = sqlc.narg(last_grade), last_response_ms = sqlc.narg(last_response_ms), last_answer = sqlc.narg(last_answer)
+WHERE user_id = sqlc.arg(user_id) AND card_id = sqlc.arg(card_id);
+
+-- name: ListDueCards :many
+SELECT flashcards.id, front, back, flashcards.set_id, set_name, due_at, interval_days, lapses
+FROM set_user
+JOIN flashcard_sets ON set_user.set_id = flashcard_sets.id
+JOIN flashcards ON flashcard_sets.id = flashcards.set_id
+LEFT JOIN card_history ON (card_history.card_id = flashcards.id AND card_history.user_id = set_user.user_id)
+WHERE set_user.user_id = $1 AND (due_at IS NULL OR due_at <= LOCALTIMESTAMP(2))
+ORDER BY due_at NULLS LAST, flashcards.id;
+
+
+-- set overrides win over class overrides; across classes the strictest (largest) threshold applies
+-- name: GetMasteryRule :one
+SELECT COALESCE(flashcard_sets.mastery_streak, MAX(classes.mastery_streak), sqlc.arg(default_streak)::int)::int AS streak,
+COALESCE(flashcard_sets.mastery_interval_days, MAX(classes.mastery_interval_days), sqlc.arg(default_interval_days)::int)::int AS interval_days
+FROM flashcards
+JOIN flashcard_sets ON flashcards.set_id = flashcard_sets.id
+LEFT JOIN class_set ON flashcard_sets.id = class_set.set_id
+LEFT JOIN class_user ON (class_set.class_id = class_user.class_id AND class_user.user_id = sqlc.arg(user_id))
+LEFT JOIN classes ON class_user.class_id = classes.id
+WHERE flashcards.id = sqlc.arg(card_id)
+GROUP BY flashcard_sets.id;
+
+-- name: ListMasteryCountsOfAUser :many
+SELECT flashcard_sets.id AS set_id, set_name,
+COUNT(flashcards.id) FILTER (WHERE card_history.is_mastered) AS mastered,
+COUNT(flashcards.id) FILTER (WHERE NOT card_history.is_mastered) AS learning,
+COUNT(flashcards.id) FILTER (WHERE card_history.card_id IS NULL) AS new_cards
+FROM set_user
+JOIN flashcard_sets ON set_user.set_id = flashcard_sets.id
+JOIN flashcards ON flashcard_sets.id = flashcards.set_id
+LEFT JOIN card_history ON (card_history.card_id = flashcards.id AND card_history.user_id = set_user.user_id)
+WHERE set_user.user_id = $1
+GROUP BY flashcard_sets.id, set_name ORDER BY set_name;
+
+-- name: GetMasteryCountsInASet :one
+SELECT COUNT(flashcards.id) FILTER (WHERE card_history.is_mastered) AS mastered,
+COUNT(flashcards.id) FILTER (WHERE NOT card_history.is_mastered) AS learning,
+COUNT(flashcards.id) FILTER (WHERE card_history.card_id IS NULL) AS new_cards
+FROM flashcards
+LEFT JOIN card_history ON (card_history.card_id = flashcards.id AND card_history.user_id = $1)
+WHERE flashcards.set_id = $2;
+
+-- a set override changes the rule for everyone studying it, so settle existing rows right away
+-- name: RecomputeMasteryInASet :exec
+UPDATE card_history SET is_mastered = (
+  (sqlc.arg(streak)::int > 0 AND correct_streak >= sqlc.arg(streak)::int)
+  OR (sqlc.arg(interval_days)::int > 0 AND interval_days >= sqlc.arg(interval_days)::int)
+)
+WHERE card_id IN (SELECT id FROM flashcards WHERE set_id = sqlc.arg(set_id));
+
+-- name: GetCardForTypedAnswer :one
+SELECT f.back, f.set_id, s.typo_tolerance FROM flashcards AS f
+JOIN flashcard_sets AS s ON s.id = f.set_id
+WHERE f.id = $1;
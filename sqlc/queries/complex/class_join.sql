@@ -0,0 +1,31 @@
+-- regenerating replaces the code, which also re-enables it
+-- name: RegenerateJoinCode :one
+INSERT INTO class_join_codes (class_id, expires_at) VALUES (sqlc.arg(class_id), sqlc.narg(expires_at)::timestamptz::timestamp)
+ON CONFLICT (class_id) DO UPDATE SET code = EXCLUDED.code, enabled = true, expires_at = EXCLUDED.expires_at, updated_at = LOCALTIMESTAMP(2)
+RETURNING *;
+
+-- name: GetJoinCode :one
+SELECT * FROM class_join_codes WHERE class_id = $1;
+
+-- name: UpdateJoinCode :one
+UPDATE class_join_codes SET enabled = sqlc.arg(enabled), expires_at = sqlc.narg(expires_at)::timestamptz::timestamp, updated_at = LOCALTIMESTAMP(2)
+WHERE class_id = sqlc.arg(class_id)
+RETURNING *;
+
+-- name: GetClassIdByJoinCode :one
+SELECT class_id FROM class_join_codes
+WHERE code = UPPER(sqlc.arg(code)) AND enabled AND (expires_at IS NULL OR expires_at > LOCALTIMESTAMP(2));
+
+-- name: CreateClassInvite :one
+INSERT INTO class_invites (token, class_id, expires_at)
+VALUES (sqlc.arg(token), sqlc.arg(class_id), LOCALTIMESTAMP(2) + make_interval(hours => sqlc.arg(hours)::int))
+RETURNING *;
+
+-- name: GetClassIdByInvite :one
+SELECT class_id FROM class_invites WHERE token = $1 AND expires_at > LOCALTIMESTAMP(2);
+
+-- name: ListClassInvites :many
+SELECT * FROM class_invites WHERE class_id = $1 AND expires_at > LOCALTIMESTAMP(2) ORDER BY created_at DESC;
+
+-- name: DeleteClassInvite :exec
+DELETE FROM class_invites WHERE token = $1 AND class_id = $2;
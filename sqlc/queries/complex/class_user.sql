@@ -1,25 +1,38 @@
 -- name: JoinClass :exec
 INSERT INTO class_user (user_id, class_id, role) VALUES ($1, $2, $3);
 
+-- name: UpdateClassMemberRole :execrows
+UPDATE class_user SET role = $1 WHERE class_id = $2 AND user_id = $3;
+
+-- locks the teacher rows so concurrent demotions/removals can't both pass the last-teacher check
+-- name: LockTeachersOfAClass :many
+SELECT user_id FROM class_user WHERE class_id = $1 AND role = 'teacher' FOR UPDATE;
+
 -- name: LeaveClass :exec
 DELETE FROM class_user WHERE user_id = $1 AND class_id = $2;
 
 -- name: ListClassesOfAUser :many
 SELECT class_id, role, class_name, class_description FROM class_user JOIN classes ON class_user.class_id = classes.id WHERE user_id = $1 ORDER BY class_name;
 
+-- Teachers (is_teacher) see every member; everyone else misses members who are hidden, unless it's themselves
 -- name: ListMembersOfAClass :many
-SELECT user_id, class_id, role, first_name, last_name, username FROM class_user JOIN users ON class_user.user_id = users.id WHERE class_id = $1 ORDER BY last_name, first_name;
-
-
-
-
+SELECT class_user.user_id, class_user.class_id, class_user.role, users.first_name, users.last_name, users.username,
+users.leaderboard_visibility, users.nickname_seed, classes.leaderboard_visibility AS class_visibility
+FROM class_user
+JOIN users ON class_user.user_id = users.id
+JOIN classes ON class_user.class_id = classes.id
+WHERE class_user.class_id = sqlc.arg(class_id) AND (sqlc.arg(is_teacher)::bool OR class_user.user_id = sqlc.arg(viewer_id)
+  OR (users.leaderboard_visibility <> 'hidden' AND classes.leaderboard_visibility <> 'hidden'))
+ORDER BY users.last_name, users.first_name;
 
 -- name: ListStudentsOfAClass :many
--- SELECT user_id, class_id, role, first_name, last_name
--- FROM class_user JOIN users ON class_user.user_id = users.id
--- WHERE class_id = $1 AND role = 'student';
+SELECT user_id, class_id, role, first_name, last_name, username
+FROM class_user JOIN users ON class_user.user_id = users.id
+WHERE class_id = $1 AND role = 'student'
+ORDER BY last_name, first_name;
 
 -- name: ListTeachersOfAClass :many
--- SELECT user_id, class_id, role, first_name, last_name
--- FROM class_user JOIN users ON class_user.user_id = users.id
--- WHERE class_id = $1 AND role = 'teacher';
\ No newline at end of file
+SELECT user_id, class_id, role, first_name, last_name, username
+FROM class_user JOIN users ON class_user.user_id = users.id
+WHERE class_id = $1 AND role = 'teacher'
+ORDER BY last_name, first_name;
\ No newline at end of file
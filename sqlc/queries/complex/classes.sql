@@ -1,9 +1,15 @@
+-- lifetime scores. Teachers (is_teacher) see everything; everyone else misses the scores of
+-- sets other members marked private and members who are hidden, unless it's themselves
 -- name: GetClassLeaderboard :many
-SELECT class_user.user_id, users.first_name, users.last_name, users.username, COALESCE(SUM(set_score),0) AS class_score FROM classes 
+SELECT class_user.user_id, users.first_name, users.last_name, users.username,
+COALESCE(SUM(set_score) FILTER (WHERE NOT set_user.is_private OR sqlc.arg(is_teacher)::bool OR class_user.user_id = sqlc.arg(viewer_id)), 0) AS class_score,
+users.leaderboard_visibility, users.nickname_seed, classes.leaderboard_visibility AS class_visibility
+FROM classes
 JOIN class_user ON classes.id = class_user.class_id 
 JOIN class_set ON classes.id = class_set.class_id
 JOIN set_user ON (class_user.user_id = set_user.user_id AND class_set.set_id = set_user.set_id)
 JOIN users ON class_user.user_id = users.id
-WHERE classes.id = $1
-GROUP BY class_user.user_id, users.first_name, users.last_name, users.username
-ORDER BY COALESCE(SUM(set_score),0) DESC;
\ No newline at end of file
+WHERE classes.id = sqlc.arg(class_id) AND (sqlc.arg(is_teacher)::bool OR class_user.user_id = sqlc.arg(viewer_id)
+  OR (users.leaderboard_visibility <> 'hidden' AND classes.leaderboard_visibility <> 'hidden'))
+GROUP BY class_user.user_id, users.first_name, users.last_name, users.username, users.leaderboard_visibility, users.nickname_seed, classes.leaderboard_visibility
+ORDER BY class_score DESC, class_user.user_id;
\ No newline at end of file
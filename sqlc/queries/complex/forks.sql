@@ -0,0 +1,25 @@
+-- name: ForkFlashcardSet :one
+INSERT INTO flashcard_sets (set_name, set_description, mastery_streak, mastery_interval_days, typo_tolerance, forked_from, forked_at)
+SELECT COALESCE(sqlc.narg(set_name)::text, up.set_name), up.set_description, up.mastery_streak, up.mastery_interval_days, up.typo_tolerance, up.id, LOCALTIMESTAMP(2)
+FROM flashcard_sets AS up WHERE up.id = sqlc.arg(upstream_set_id)
+RETURNING *;
+
+-- name: ForkFlashcards :execrows
+INSERT INTO flashcards (front, back, set_id, upstream_id)
+SELECT up.front, up.back, sqlc.arg(fork_set_id)::int, up.id FROM flashcards AS up WHERE up.set_id = sqlc.arg(upstream_set_id) ORDER BY up.id;
+
+-- upstream cards the fork doesn't have are new, forked cards whose upstream card is gone (or
+-- moved to another set) are removed, and upstream cards edited since the fork that no longer
+-- match the fork's copy are changed. Cards added to the fork itself aren't part of the diff
+-- name: DiffForkWithUpstream :many
+SELECT
+  (CASE WHEN mine.id IS NULL THEN 'new' WHEN up.id IS NULL THEN 'removed' ELSE 'changed' END)::text AS change,
+  COALESCE(mine.id, 0) AS card_id, COALESCE(mine.front, '') AS front, COALESCE(mine.back, '') AS back,
+  COALESCE(up.id, mine.upstream_id) AS upstream_card_id,
+  COALESCE(up.front, '') AS upstream_front, COALESCE(up.back, '') AS upstream_back
+FROM (SELECT id, front, back, upstream_id FROM flashcards WHERE flashcards.set_id = sqlc.arg(set_id) AND upstream_id IS NOT NULL) AS mine
+FULL JOIN (SELECT id, front, back, updated_at FROM flashcards WHERE flashcards.set_id = sqlc.arg(upstream_set_id)) AS up
+ON mine.upstream_id = up.id
+WHERE mine.id IS NULL OR up.id IS NULL
+OR (up.updated_at > sqlc.arg(forked_at)::timestamp AND (mine.front <> up.front OR mine.back <> up.back))
+ORDER BY upstream_card_id, card_id;
@@ -0,0 +1,51 @@
+-- the score is the number of correct answers in [window_start, window_end), the same thing
+-- set_score counts over all time. Ties go to whoever reached their score first, then the lower id.
+-- Privacy works like GetClassLeaderboard: hidden members and private sets only show to
+-- teachers and the member themselves
+
+-- name: ListClassLeaderboard :many
+SELECT cu.user_id, u.first_name, u.last_name, u.username,
+(COUNT(rl.id) FILTER (WHERE rl.grade <> 'again'))::int AS score,
+COUNT(rl.id)::int AS reviews,
+(MAX(rl.reviewed_at) FILTER (WHERE rl.grade <> 'again'))::timestamp AS reached_at,
+u.leaderboard_visibility, u.nickname_seed, c.leaderboard_visibility AS class_visibility
+FROM class_user AS cu
+JOIN users AS u ON u.id = cu.user_id
+JOIN classes AS c ON c.id = cu.class_id
+LEFT JOIN review_log AS rl ON rl.user_id = cu.user_id
+  AND rl.reviewed_at >= sqlc.arg(window_start)::timestamptz::timestamp
+  AND rl.reviewed_at < sqlc.arg(window_end)::timestamptz::timestamp
+  AND rl.card_id IN (
+    SELECT f.id FROM flashcards AS f
+    JOIN class_set AS cs ON cs.set_id = f.set_id
+    LEFT JOIN set_user AS su ON su.set_id = f.set_id AND su.user_id = cu.user_id
+    WHERE cs.class_id = cu.class_id
+      AND (su.is_private IS NOT TRUE OR sqlc.arg(is_teacher)::bool OR cu.user_id = sqlc.arg(viewer_id))
+  )
+WHERE cu.class_id = sqlc.arg(class_id) AND (sqlc.arg(is_teacher)::bool OR cu.user_id = sqlc.arg(viewer_id)
+  OR (u.leaderboard_visibility <> 'hidden' AND c.leaderboard_visibility <> 'hidden'))
+GROUP BY cu.user_id, u.first_name, u.last_name, u.username, u.leaderboard_visibility, u.nickname_seed, c.leaderboard_visibility
+ORDER BY score DESC, reached_at ASC NULLS LAST, cu.user_id
+LIMIT sqlc.arg(page_size)::int OFFSET sqlc.arg(page_offset)::int;
+
+-- everyone who opted in, isn't hidden and answered at least one card correctly in the window.
+-- Private sets never count here
+-- name: ListGlobalLeaderboard :many
+SELECT u.id AS user_id, u.first_name, u.last_name, u.username,
+(COUNT(rl.id) FILTER (WHERE rl.grade <> 'again'))::int AS score,
+COUNT(rl.id)::int AS reviews,
+(MAX(rl.reviewed_at) FILTER (WHERE rl.grade <> 'again'))::timestamp AS reached_at,
+u.leaderboard_visibility, u.nickname_seed
+FROM users AS u
+JOIN review_log AS rl ON rl.user_id = u.id
+WHERE u.leaderboard_opt_in AND u.leaderboard_visibility <> 'hidden'
+  AND rl.reviewed_at >= sqlc.arg(window_start)::timestamptz::timestamp
+  AND rl.reviewed_at < sqlc.arg(window_end)::timestamptz::timestamp
+  AND NOT EXISTS (
+    SELECT 1 FROM set_user AS su JOIN flashcards AS f ON f.set_id = su.set_id
+    WHERE f.id = rl.card_id AND su.user_id = u.id AND su.is_private
+  )
+GROUP BY u.id, u.first_name, u.last_name, u.username, u.leaderboard_visibility, u.nickname_seed
+HAVING COUNT(rl.id) FILTER (WHERE rl.grade <> 'again') > 0
+ORDER BY score DESC, reached_at ASC, u.id
+LIMIT sqlc.arg(page_size)::int OFFSET sqlc.arg(page_offset)::int;
@@ -0,0 +1,17 @@
+-- name: CreateQuiz :one
+INSERT INTO quizzes (user_id, set_id, seed) VALUES ($1, $2, $3) RETURNING *;
+
+-- name: AddQuizQuestion :exec
+INSERT INTO quiz_questions (quiz_id, position, card_id, prompt, choices, answer_index) VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: GetQuiz :one
+SELECT * FROM quizzes WHERE id = $1 AND user_id = $2;
+
+-- name: ListQuizQuestions :many
+SELECT * FROM quiz_questions WHERE quiz_id = $1 ORDER BY position;
+
+-- answering twice would count the card twice, so only the first answer sticks
+-- name: AnswerQuizQuestion :one
+UPDATE quiz_questions SET chosen_index = $1, answered_at = LOCALTIMESTAMP(2)
+WHERE quiz_id = $2 AND position = $3 AND chosen_index IS NULL
+RETURNING card_id, answer_index;
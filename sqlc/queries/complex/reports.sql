@@ -0,0 +1,61 @@
+-- one row per student per set of the class. card_history only keeps the last response time,
+-- so time on task is summed from review_log
+-- name: GetClassProgressReport :many
+SELECT cu.user_id, u.first_name, u.last_name, u.username, cs.set_id, s.set_name,
+COUNT(f.id)::int AS total_cards,
+COUNT(ch.card_id)::int AS cards_studied,
+(COUNT(ch.card_id) FILTER (WHERE ch.is_mastered))::int AS cards_mastered,
+COALESCE(SUM(ch.score), 0)::int AS correct,
+COALESCE(SUM(ch.times_attempted), 0)::int AS attempts,
+MAX(ch.last_reviewed_at)::timestamp AS last_studied_at,
+COALESCE((
+  SELECT SUM(rl.response_ms) FROM review_log AS rl JOIN flashcards AS rf ON rf.id = rl.card_id
+  WHERE rl.user_id = cu.user_id AND rf.set_id = cs.set_id
+), 0)::bigint AS time_ms
+FROM class_user AS cu
+JOIN users AS u ON u.id = cu.user_id
+JOIN class_set AS cs ON cs.class_id = cu.class_id
+JOIN flashcard_sets AS s ON s.id = cs.set_id
+LEFT JOIN flashcards AS f ON f.set_id = cs.set_id
+LEFT JOIN card_history AS ch ON ch.card_id = f.id AND ch.user_id = cu.user_id
+WHERE cu.class_id = sqlc.arg(class_id) AND cu.role = 'student' AND (sqlc.narg(student_id)::int IS NULL OR cu.user_id = sqlc.narg(student_id)::int)
+GROUP BY cu.user_id, u.first_name, u.last_name, u.username, cs.set_id, s.set_name
+ORDER BY u.last_name, u.first_name, cu.user_id, s.set_name, cs.set_id;
+
+
+-- the class's cards, hardest first, up to max_cards (all of them when null). A student
+-- struggled with a card when they missed it on at least half of their attempts; cards
+-- nobody tried go last. card_history rows without attempts don't count as trying
+-- name: ListHardestCardsInAClass :many
+SELECT f.id AS card_id, f.set_id, s.set_name, f.front, f.back,
+(SELECT COUNT(*) FROM class_user WHERE class_user.class_id = sqlc.arg(class_id) AND role = 'student')::int AS class_students,
+(COUNT(ch.user_id) FILTER (WHERE ch.times_attempted > 0))::int AS students_attempted,
+(COUNT(ch.user_id) FILTER (WHERE ch.times_attempted > 0 AND (ch.times_attempted - ch.score) * 2 >= ch.times_attempted))::int AS students_struggled,
+COALESCE(SUM(ch.score), 0)::int AS correct,
+COALESCE(SUM(ch.times_attempted), 0)::int AS attempts
+FROM class_set AS cs
+JOIN flashcard_sets AS s ON s.id = cs.set_id
+JOIN flashcards AS f ON f.set_id = cs.set_id
+LEFT JOIN card_history AS ch ON ch.card_id = f.id
+  AND ch.user_id IN (SELECT user_id FROM class_user WHERE class_user.class_id = cs.class_id AND role = 'student')
+WHERE cs.class_id = sqlc.arg(class_id)
+GROUP BY f.id, s.set_name
+ORDER BY SUM(ch.score)::float / NULLIF(SUM(ch.times_attempted), 0) ASC NULLS LAST, students_struggled DESC, attempts DESC, f.id
+LIMIT sqlc.narg(max_cards)::int;
+
+
+-- one row per student per assignment of the class; students and assignments without
+-- rows here still get a gradebook row/column from their own lists
+-- name: GetClassGradebook :many
+SELECT cu.user_id, a.id AS assignment_id, a.required_mastery_pct, a.min_reviews,
+COALESCE(su.set_score, 0)::int AS score,
+COUNT(f.id)::int AS total_cards,
+(COUNT(f.id) FILTER (WHERE ch.is_mastered))::int AS mastered_cards,
+COALESCE(SUM(ch.times_attempted), 0)::int AS reviews
+FROM class_user AS cu
+JOIN assignments AS a ON a.class_id = cu.class_id
+LEFT JOIN set_user AS su ON su.user_id = cu.user_id AND su.set_id = a.set_id
+LEFT JOIN flashcards AS f ON f.set_id = a.set_id
+LEFT JOIN card_history AS ch ON ch.card_id = f.id AND ch.user_id = cu.user_id
+WHERE cu.class_id = $1 AND cu.role = 'student'
+GROUP BY cu.user_id, a.id, su.set_score;
@@ -0,0 +1,38 @@
+-- append-only, one row per answer; card_history keeps the running totals
+
+-- name: LogReview :exec
+INSERT INTO review_log (user_id, card_id, grade, response_ms, answer, interval_days, ease_factor, client_event_id, reviewed_at)
+VALUES (sqlc.arg(user_id), sqlc.arg(card_id), sqlc.arg(grade), sqlc.narg(response_ms), sqlc.narg(answer), sqlc.arg(interval_days),
+sqlc.arg(ease_factor), sqlc.narg(client_event_id), COALESCE(sqlc.narg(reviewed_at)::timestamptz::timestamp, LOCALTIMESTAMP(2)));
+
+-- name: IsReviewLogged :one
+SELECT EXISTS (SELECT 1 FROM review_log WHERE user_id = $1 AND client_event_id = $2);
+
+-- name: ListReviewsOfACard :many
+SELECT id, grade, response_ms, answer, interval_days, ease_factor, reviewed_at FROM review_log
+WHERE user_id = $1 AND card_id = $2 ORDER BY reviewed_at DESC, id DESC;
+
+-- name: ListReviewsInASet :many
+SELECT review_log.id, card_id, front, back, grade, response_ms, answer, interval_days, reviewed_at FROM review_log
+JOIN flashcards ON review_log.card_id = flashcards.id
+WHERE user_id = $1 AND set_id = $2 ORDER BY reviewed_at DESC, review_log.id DESC;
+
+-- oldest first per card, the order an export replays them in
+-- name: ListReviewHistoryInASet :many
+SELECT review_log.id, card_id, grade, response_ms, interval_days, ease_factor, reviewed_at FROM review_log
+JOIN flashcards ON review_log.card_id = flashcards.id
+WHERE user_id = $1 AND set_id = $2 ORDER BY card_id, reviewed_at, review_log.id;
+
+-- name: ListDailyReviews :many
+SELECT reviewed_at::date AS day, COUNT(*) AS reviews, COUNT(*) FILTER (WHERE grade <> 'again') AS correct,
+COUNT(DISTINCT card_id) AS cards, COALESCE(SUM(response_ms), 0)::bigint AS time_ms
+FROM review_log WHERE user_id = $1
+GROUP BY day ORDER BY day DESC;
+
+-- name: ListDailyReviewsInAClass :many
+SELECT reviewed_at::date AS day, COUNT(*) AS reviews, COUNT(*) FILTER (WHERE grade <> 'again') AS correct,
+COUNT(DISTINCT card_id) AS cards, COALESCE(SUM(response_ms), 0)::bigint AS time_ms
+FROM review_log WHERE user_id = $1 AND card_id IN (
+  SELECT flashcards.id FROM flashcards JOIN class_set ON flashcards.set_id = class_set.set_id WHERE class_id = $2
+)
+GROUP BY day ORDER BY day DESC;
@@ -0,0 +1,31 @@
+-- revisions are written by a trigger, this sets who they're credited to for the rest of
+-- the transaction
+
+-- name: SetRevisionAuthor :exec
+SELECT set_config('cowboy_cards.user_id', sqlc.arg(user_id)::int::text, true);
+
+-- name: ListRevisionsOfASet :many
+SELECT revisions.id, card_id, revisions.user_id, COALESCE(username, '')::text AS username, op, before_values, after_values, revisions.created_at
+FROM revisions LEFT JOIN users ON revisions.user_id = users.id
+WHERE set_id = sqlc.arg(set_id) ORDER BY revisions.id DESC
+LIMIT sqlc.arg(page_size) OFFSET sqlc.arg(page_offset);
+
+-- newest first, the order they're undone in
+-- name: ListRevisionsAfter :many
+SELECT id, card_id, op, before_values, after_values FROM revisions
+WHERE set_id = sqlc.arg(set_id) AND id > sqlc.arg(revision_id)::int ORDER BY id DESC;
+
+-- name: RevisionExists :one
+SELECT EXISTS (SELECT 1 FROM revisions WHERE set_id = $1 AND id = $2);
+
+-- brings back a deleted card under its old id, or puts back an edited one. A card that has
+-- since moved to another set is left where it is, no rows are affected
+-- name: RestoreFlashcard :execrows
+INSERT INTO flashcards (id, front, back, set_id) VALUES ($1, $2, $3, $4)
+ON CONFLICT (id) DO UPDATE SET front = EXCLUDED.front, back = EXCLUDED.back, updated_at = LOCALTIMESTAMP(2)
+WHERE flashcards.set_id = EXCLUDED.set_id;
+
+-- name: RestoreFlashcardSet :exec
+UPDATE flashcard_sets SET set_name = $1, set_description = $2, mastery_streak = $3, mastery_interval_days = $4,
+typo_tolerance = $5, updated_at = LOCALTIMESTAMP(2)
+WHERE id = $6;
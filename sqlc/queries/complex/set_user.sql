@@ -1,8 +1,15 @@
 -- name: JoinSet :exec
 INSERT INTO set_user (user_id, set_id, role) VALUES ($1, $2, $3);
 
+-- name: PromoteSetMember :execrows
+UPDATE set_user SET role = 'owner' WHERE set_id = $1 AND user_id = $2;
+
+-- a private set's score is left out of what other students see on leaderboards
+-- name: UpdateSetUserPrivacy :execrows
+UPDATE set_user SET is_private = $1 WHERE set_id = $2 AND user_id = $3;
+
 -- name: LeaveSet :exec
 DELETE FROM set_user WHERE user_id = $1 AND set_id = $2;
 
 -- name: ListSetsOfAUser :many
-SELECT set_id, role, set_name, set_description FROM set_user JOIN flashcard_sets ON set_user.set_id = flashcard_sets.id WHERE user_id = $1 ORDER BY set_name;
+SELECT set_id, role, is_private, set_name, set_description FROM set_user JOIN flashcard_sets ON set_user.set_id = flashcard_sets.id WHERE user_id = $1 ORDER BY set_name;
@@ -0,0 +1,31 @@
+-- a set can be read by its members, by members of classes it's been added to unless it's
+-- private, by anyone with the link token while it's unlisted and by everyone once it's public
+
+-- name: CanReadSet :one
+SELECT EXISTS (
+  SELECT 1 FROM flashcard_sets AS s WHERE s.id = sqlc.arg(set_id) AND (
+    s.visibility = 'public'
+    OR (s.visibility = 'unlisted' AND s.share_token = sqlc.arg(share_token)::text)
+    OR EXISTS (
+      SELECT 1 FROM set_user WHERE set_user.set_id = s.id AND set_user.user_id = sqlc.arg(user_id)
+    )
+    OR (s.visibility <> 'private' AND EXISTS (
+      SELECT 1 FROM class_set JOIN class_user ON class_set.class_id = class_user.class_id
+      WHERE class_set.set_id = s.id AND class_user.user_id = sqlc.arg(user_id)
+    ))
+  )
+)::bool AS can_read;
+
+-- public sets plus every other set the user can already read. Unlisted sets only show up
+-- for their members since the link is the only way in for anyone else
+-- name: ListFlashcardSets :many
+SELECT s.id, s.set_name, s.set_description, s.visibility, s.created_at, s.updated_at FROM flashcard_sets AS s
+WHERE s.visibility = 'public'
+OR EXISTS (
+  SELECT 1 FROM set_user WHERE set_user.set_id = s.id AND set_user.user_id = $1
+)
+OR (s.visibility <> 'private' AND EXISTS (
+  SELECT 1 FROM class_set JOIN class_user ON class_set.class_id = class_user.class_id
+  WHERE class_set.set_id = s.id AND class_user.user_id = $1
+))
+ORDER BY s.set_name;
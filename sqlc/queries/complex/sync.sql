@@ -0,0 +1,114 @@
+-- a change is only handed out once every transaction older than it has finished, that is
+-- when its tx_id is below the oldest transaction still running (the snapshot's xmin, the
+-- horizon). Reading in (tx_id, id) order up to the horizon, nothing can commit behind a
+-- cursor. Changes at or after a snapshot's horizon can be sent again, applying them twice is harmless
+
+-- name: GetSyncHorizon :one
+SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint AS horizon;
+
+-- name: ListChangesForUser :many
+SELECT id, tx_id, entity, op, set_id, card_id, class_id FROM change_log
+WHERE (tx_id, id) > (sqlc.arg(cursor_tx_id)::bigint, sqlc.arg(cursor_id)::bigint) AND tx_id < sqlc.arg(horizon)::bigint AND (
+  -- set changes reach class members of private sets too, so a set going private can be
+  -- tombstoned. What's loaded for them is filtered by FilterVisibleSetIds
+  (entity = 'flashcard_sets' AND set_id IN (
+    SELECT set_user.set_id FROM set_user WHERE set_user.user_id = sqlc.arg(user_id)
+    UNION
+    SELECT class_set.set_id FROM class_set JOIN class_user ON class_set.class_id = class_user.class_id
+    WHERE class_user.user_id = sqlc.arg(user_id)
+  ))
+  OR (entity = 'flashcards' AND set_id IN (
+    SELECT set_user.set_id FROM set_user WHERE set_user.user_id = sqlc.arg(user_id)
+    UNION
+    SELECT class_set.set_id FROM class_set JOIN class_user ON class_set.class_id = class_user.class_id
+    JOIN flashcard_sets ON flashcard_sets.id = class_set.set_id
+    WHERE class_user.user_id = sqlc.arg(user_id) AND flashcard_sets.visibility <> 'private'
+  ))
+  OR (entity IN ('set_user', 'class_user', 'card_history') AND change_log.user_id = sqlc.arg(user_id))
+  OR (entity = 'class_set' AND class_id IN (
+    SELECT class_user.class_id FROM class_user WHERE class_user.user_id = sqlc.arg(user_id)
+  ))
+)
+ORDER BY tx_id, id LIMIT sqlc.arg(page_size);
+
+-- keeps the newest pruned transaction so cursors from before it can be turned away
+
+-- name: PruneChangeLog :exec
+WITH pruned AS (
+  DELETE FROM change_log WHERE changed_at < LOCALTIMESTAMP - make_interval(days => sqlc.arg(retention_days)::int)
+  RETURNING tx_id
+)
+INSERT INTO change_log_pruned (tx_id) SELECT MAX(tx_id) FROM pruned HAVING COUNT(*) > 0
+ON CONFLICT (id) DO UPDATE SET tx_id = GREATEST(change_log_pruned.tx_id, EXCLUDED.tx_id);
+
+-- name: GetChangeLogPruned :one
+SELECT COALESCE(MAX(tx_id), 0)::bigint AS tx_id FROM change_log_pruned;
+
+-- name: ListVisibleSetIds :many
+SELECT set_user.set_id FROM set_user WHERE set_user.user_id = $1
+UNION
+SELECT class_set.set_id FROM class_set JOIN class_user ON class_set.class_id = class_user.class_id
+JOIN flashcard_sets ON flashcard_sets.id = class_set.set_id
+WHERE class_user.user_id = $1 AND flashcard_sets.visibility <> 'private';
+
+-- the sets of set_ids the user may sync: their own, or a class's unless it's private
+-- name: FilterVisibleSetIds :many
+SELECT id FROM flashcard_sets
+WHERE id = ANY(sqlc.arg(set_ids)::int[]) AND (
+  EXISTS (SELECT 1 FROM set_user WHERE set_user.set_id = flashcard_sets.id AND set_user.user_id = sqlc.arg(user_id))
+  OR (visibility <> 'private' AND EXISTS (
+    SELECT 1 FROM class_set JOIN class_user ON class_set.class_id = class_user.class_id
+    WHERE class_set.set_id = flashcard_sets.id AND class_user.user_id = sqlc.arg(user_id)
+  ))
+);
+
+-- everything but the share token, which only owners get
+-- name: ListSyncSets :many
+SELECT id, set_name, set_description, mastery_streak, mastery_interval_days, typo_tolerance, visibility, created_at, updated_at
+FROM flashcard_sets WHERE id = ANY(sqlc.arg(set_ids)::int[]);
+
+-- name: ListSyncFlashcards :many
+SELECT * FROM flashcards WHERE id = ANY(sqlc.arg(card_ids)::int[]) OR set_id = ANY(sqlc.arg(set_ids)::int[]);
+
+-- name: ListSyncSetUser :many
+SELECT * FROM set_user WHERE user_id = sqlc.arg(user_id) AND set_id = ANY(sqlc.arg(set_ids)::int[]);
+
+-- name: ListSyncClassSet :many
+SELECT * FROM class_set WHERE class_id = ANY(sqlc.arg(class_ids)::int[]);
+
+-- name: ListSyncClassUser :many
+SELECT * FROM class_user WHERE user_id = sqlc.arg(user_id) AND class_id = ANY(sqlc.arg(class_ids)::int[]);
+
+-- name: ListSyncCardHistory :many
+SELECT * FROM card_history WHERE user_id = sqlc.arg(user_id) AND (
+  card_id = ANY(sqlc.arg(card_ids)::int[])
+  OR card_id IN (SELECT id FROM flashcards WHERE set_id = ANY(sqlc.arg(set_ids)::int[]))
+);
+
+-- pushed edits only apply to the version the device last saw (updated_at); zero rows means conflict or gone
+
+-- name: SyncUpdateFlashcardSet :one
+UPDATE flashcard_sets SET set_name = sqlc.arg(set_name), set_description = sqlc.arg(set_description), updated_at = LOCALTIMESTAMP(2)
+WHERE id = sqlc.arg(id) AND updated_at = sqlc.arg(base_updated_at) RETURNING *;
+
+-- name: SyncUpdateFlashcard :one
+UPDATE flashcards SET front = sqlc.arg(front), back = sqlc.arg(back), updated_at = LOCALTIMESTAMP(2)
+WHERE id = sqlc.arg(id) AND updated_at = sqlc.arg(base_updated_at) RETURNING *;
+
+-- name: SyncDeleteFlashcard :execrows
+DELETE FROM flashcards WHERE id = sqlc.arg(id) AND updated_at = sqlc.arg(base_updated_at);
+
+-- a pushed create claims its client_id first; zero rows means an earlier push already made the card
+
+-- name: ClaimSyncCreate :execrows
+INSERT INTO sync_creates (user_id, client_id) VALUES (sqlc.arg(user_id), sqlc.arg(client_id))
+ON CONFLICT (user_id, client_id) DO NOTHING;
+
+-- name: SetSyncCreateCard :exec
+UPDATE sync_creates SET card_id = sqlc.arg(card_id) WHERE user_id = sqlc.arg(user_id) AND client_id = sqlc.arg(client_id);
+
+-- name: GetSyncCreate :one
+SELECT * FROM sync_creates WHERE user_id = sqlc.arg(user_id) AND client_id = sqlc.arg(client_id);
+
+-- name: PruneSyncCreates :exec
+DELETE FROM sync_creates WHERE created_at < LOCALTIMESTAMP - make_interval(days => sqlc.arg(retention_days)::int);
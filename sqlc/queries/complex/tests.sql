@@ -0,0 +1,66 @@
+-- name: CreateTest :one
+INSERT INTO tests (class_id, test_name, question_count, time_limit_seconds, max_attempts) VALUES ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: AddSetToTest :exec
+INSERT INTO test_sets (test_id, set_id) VALUES ($1, $2);
+
+-- name: CountSetsInAClass :one
+SELECT COUNT(*)::int FROM class_set WHERE class_id = sqlc.arg(class_id) AND set_id = ANY(sqlc.arg(set_ids)::int[]);
+
+-- name: ListTestsInAClass :many
+SELECT * FROM tests WHERE class_id = $1 ORDER BY created_at DESC;
+
+-- name: GetTestOfAMember :one
+SELECT t.*, cu.role FROM tests AS t
+JOIN class_user AS cu ON cu.class_id = t.class_id AND cu.user_id = $2
+WHERE t.id = $1;
+
+-- name: ListTestCards :many
+SELECT DISTINCT f.id, f.front, f.back FROM flashcards AS f
+JOIN test_sets AS ts ON ts.set_id = f.set_id
+WHERE ts.test_id = $1;
+
+-- open until GetTestAttempt calls it expired, grace period included
+-- name: GetOpenTestAttempt :one
+SELECT * FROM test_attempts
+WHERE test_id = $1 AND user_id = $2 AND submitted_at IS NULL AND deadline + interval '30 seconds' >= LOCALTIMESTAMP(2);
+
+-- no row comes back once the student has used max_attempts, expired attempts included
+-- name: StartTestAttempt :one
+INSERT INTO test_attempts (test_id, user_id, attempt, seed, total, deadline)
+SELECT sqlc.arg(test_id)::int, sqlc.arg(user_id)::int, COUNT(*)::int + 1, sqlc.arg(seed)::bigint, sqlc.arg(total)::int,
+LOCALTIMESTAMP(2) + make_interval(secs => sqlc.arg(time_limit_seconds)::int)
+FROM test_attempts
+WHERE test_id = sqlc.arg(test_id) AND user_id = sqlc.arg(user_id)
+HAVING COUNT(*) < sqlc.arg(max_attempts)::int
+RETURNING *;
+
+-- name: AddTestAttemptQuestion :exec
+INSERT INTO test_attempt_questions (attempt_id, position, card_id, prompt, choices, answer_index) VALUES ($1, $2, $3, $4, $5, $6);
+
+-- expired allows a short grace period for the submit request to arrive
+-- name: GetTestAttempt :one
+SELECT a.*, t.class_id, (a.deadline + interval '30 seconds' < LOCALTIMESTAMP(2))::bool AS expired
+FROM test_attempts AS a
+JOIN tests AS t ON t.id = a.test_id
+WHERE a.id = $1;
+
+-- name: ListTestAttemptQuestions :many
+SELECT * FROM test_attempt_questions WHERE attempt_id = $1 ORDER BY position;
+
+-- name: AnswerTestAttemptQuestion :exec
+UPDATE test_attempt_questions SET chosen_index = $1 WHERE attempt_id = $2 AND position = $3;
+
+-- name: SubmitTestAttempt :one
+UPDATE test_attempts SET submitted_at = LOCALTIMESTAMP(2), score = (
+  SELECT COUNT(*) FROM test_attempt_questions AS q WHERE q.attempt_id = test_attempts.id AND q.chosen_index = q.answer_index
+)
+WHERE id = $1 AND submitted_at IS NULL
+RETURNING *;
+
+-- name: ListTestAttempts :many
+SELECT a.id, a.user_id, u.username, u.first_name, u.last_name, a.score, a.total, a.started_at, a.deadline, a.submitted_at
+FROM test_attempts AS a
+JOIN users AS u ON u.id = a.user_id
+WHERE a.test_id = $1
+ORDER BY u.last_name, u.first_name, a.started_at;
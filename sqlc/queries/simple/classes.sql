@@ -13,6 +13,12 @@ UPDATE classes SET class_name = $1, updated_at = LOCALTIMESTAMP(2) WHERE id = $2
 -- name: UpdateClassDescription :one
 UPDATE classes SET class_description = $1, updated_at = LOCALTIMESTAMP(2) WHERE id = $2 RETURNING class_description;
 
+-- name: UpdateClassMasteryRule :exec
+UPDATE classes SET mastery_streak = $1, mastery_interval_days = $2, updated_at = LOCALTIMESTAMP(2) WHERE id = $3;
+
+-- name: UpdateClassLeaderboardVisibility :exec
+UPDATE classes SET leaderboard_visibility = $1, updated_at = LOCALTIMESTAMP(2) WHERE id = $2;
+
 -- name: DeleteClass :exec
 DELETE FROM classes WHERE id = $1;
 
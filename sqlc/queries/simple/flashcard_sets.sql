@@ -1,6 +1,3 @@
--- name: ListFlashcardSets :many
-SELECT * FROM flashcard_sets ORDER BY set_name;
-
 -- name: GetFlashcardSetById :one
 SELECT * FROM flashcard_sets WHERE id = $1;
 
@@ -13,6 +10,20 @@ UPDATE flashcard_sets SET set_name = $1, updated_at = LOCALTIMESTAMP(2) WHERE id
 -- name: UpdateFlashcardSetDescription :one
 UPDATE flashcard_sets SET set_description = $1, updated_at = LOCALTIMESTAMP(2) WHERE id = $2 RETURNING set_description;
 
+-- name: UpdateFlashcardSetMasteryRule :exec
+UPDATE flashcard_sets SET mastery_streak = $1, mastery_interval_days = $2, updated_at = LOCALTIMESTAMP(2) WHERE id = $3;
+
+-- name: UpdateFlashcardSetTypoTolerance :exec
+UPDATE flashcard_sets SET typo_tolerance = $1, updated_at = LOCALTIMESTAMP(2) WHERE id = $2;
+
+-- the link token only exists while the set is unlisted, and going back to unlisted later
+-- gets a new one so old links stop working
+-- name: UpdateFlashcardSetVisibility :one
+UPDATE flashcard_sets SET
+  share_token = CASE WHEN visibility = 'unlisted' AND sqlc.arg(visibility)::text = 'unlisted' THEN share_token ELSE sqlc.narg(share_token) END,
+  visibility = sqlc.arg(visibility), updated_at = LOCALTIMESTAMP(2)
+WHERE id = sqlc.arg(id) RETURNING visibility, share_token;
+
 -- name: DeleteFlashcardSet :exec
 DELETE FROM flashcard_sets WHERE id = $1;
 
@@ -2,7 +2,7 @@
 SELECT id, username, first_name, last_name, email, created_at, updated_at FROM users ORDER BY last_name, first_name;
 
 -- name: GetUserById :one
-SELECT id, username, first_name, last_name, email, login_streak, created_at, updated_at FROM users WHERE id = $1;
+SELECT id, username, first_name, last_name, email, login_streak, leaderboard_opt_in, leaderboard_visibility, created_at, updated_at FROM users WHERE id = $1;
 
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE email = $1;
@@ -31,6 +31,12 @@ UPDATE users SET password = $1, updated_at = LOCALTIMESTAMP(2) WHERE id = $2;
 -- name: UpdateLastLogin :exec
 UPDATE users SET last_login = CURRENT_DATE, updated_at = LOCALTIMESTAMP(2) WHERE id = $1;
 
+-- name: UpdateLeaderboardOptIn :exec
+UPDATE users SET leaderboard_opt_in = $1, updated_at = LOCALTIMESTAMP(2) WHERE id = $2;
+
+-- name: UpdateLeaderboardVisibility :exec
+UPDATE users SET leaderboard_visibility = $1, updated_at = LOCALTIMESTAMP(2) WHERE id = $2;
+
 -- name: DeleteUser :exec
 DELETE FROM users WHERE id = $1;
 
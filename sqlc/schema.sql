@@ -8,6 +8,11 @@ create table users (
   reset_token TEXT,
   last_login DATE not null default CURRENT_DATE,
   login_streak INTEGER not null default 1,
+  leaderboard_opt_in BOOLEAN not null default false,
+  leaderboard_visibility TEXT not null default 'full' check (
+    leaderboard_visibility in ('full', 'username', 'nickname', 'hidden')
+  ),
+  nickname_seed INTEGER not null default floor(random() * 2147483647)::int,
   created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
   updated_at TIMESTAMP not null default LOCALTIMESTAMP(2),
   check (LENGTH(password) >= 8),
@@ -18,16 +23,29 @@ create table flashcard_sets (
   id SERIAL,
   set_name TEXT not null,
   set_description TEXT not null,
+  mastery_streak INTEGER check (mastery_streak >= 0),
+  mastery_interval_days INTEGER check (mastery_interval_days >= 0),
+  typo_tolerance INTEGER not null default 1 check (typo_tolerance >= 0),
+  visibility TEXT not null default 'class' check (
+    visibility in ('private', 'class', 'unlisted', 'public')
+  ),
+  share_token TEXT unique,
+  forked_from INTEGER,
+  forked_at TIMESTAMP,
   created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
   updated_at TIMESTAMP not null default LOCALTIMESTAMP(2),
-  primary key (id)
+  primary key (id),
+  foreign KEY (forked_from) references flashcard_sets (id) on delete set null on update CASCADE
 );
 
+-- upstream_id is the card a forked card was copied from. It has no foreign key so the fork
+-- can still tell when the upstream card is deleted
 create table flashcards (
   id SERIAL,
   front TEXT not null,
   back TEXT not null,
   set_id INTEGER not null,
+  upstream_id INTEGER,
   created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
   updated_at TIMESTAMP not null default LOCALTIMESTAMP(2),
   primary key (id),
@@ -38,6 +56,11 @@ create table classes (
   id SERIAL,
   class_name TEXT not null,
   class_description TEXT not null,
+  mastery_streak INTEGER check (mastery_streak >= 0),
+  mastery_interval_days INTEGER check (mastery_interval_days >= 0),
+  leaderboard_visibility TEXT not null default 'full' check (
+    leaderboard_visibility in ('full', 'username', 'nickname', 'hidden')
+  ),
   created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
   updated_at TIMESTAMP not null default LOCALTIMESTAMP(2),
   primary key (id)
@@ -49,6 +72,16 @@ create table card_history (
   score INTEGER default 0 not null,
   times_attempted INTEGER default 1 not null,
   is_mastered BOOLEAN not null default false,
+  ease_factor DOUBLE PRECISION not null default 2.5,
+  interval_days INTEGER not null default 0,
+  repetitions INTEGER not null default 0,
+  lapses INTEGER not null default 0,
+  correct_streak INTEGER not null default 0,
+  due_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  last_reviewed_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  last_grade TEXT check (last_grade in ('again', 'hard', 'good', 'easy')),
+  last_response_ms INTEGER check (last_response_ms >= 0),
+  last_answer TEXT,
   created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
   primary key (user_id, card_id),
   foreign KEY (user_id) references users (id) on delete CASCADE on update CASCADE,
@@ -81,4 +114,204 @@ create table set_user (
   primary key (user_id, set_id),
   foreign KEY (user_id) references users (id) on delete CASCADE on update CASCADE,
   foreign KEY (set_id) references flashcard_sets (id) on delete CASCADE on update CASCADE
+);
+
+create table review_log (
+  id SERIAL,
+  user_id INTEGER not null,
+  card_id INTEGER not null,
+  grade TEXT not null check (grade in ('again', 'hard', 'good', 'easy')),
+  response_ms INTEGER check (response_ms >= 0),
+  answer TEXT,
+  interval_days INTEGER not null,
+  ease_factor DOUBLE PRECISION not null,
+  client_event_id TEXT,
+  reviewed_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (id),
+  unique (user_id, client_event_id),
+  foreign KEY (user_id) references users (id) on delete CASCADE on update CASCADE,
+  foreign KEY (card_id) references flashcards (id) on delete CASCADE on update CASCADE
+);
+
+create index review_log_user_reviewed_at on review_log (user_id, reviewed_at);
+
+-- one row per edit of a set or one of its cards, before_values and after_values hold the
+-- edited fields (null for a create or delete). card_id has no foreign key so a deleted
+-- card's history stays; user_id is set per transaction by the api and null if it wasn't
+create table revisions (
+  id SERIAL,
+  set_id INTEGER not null,
+  card_id INTEGER,
+  user_id INTEGER,
+  op TEXT not null check (op in ('create', 'update', 'delete')),
+  before_values JSONB,
+  after_values JSONB,
+  created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (id),
+  foreign KEY (set_id) references flashcard_sets (id) on delete CASCADE on update CASCADE,
+  foreign KEY (user_id) references users (id) on delete set null on update CASCADE
+);
+
+create index revisions_set_id on revisions (set_id, id);
+
+create table change_log (
+  id BIGSERIAL,
+  entity TEXT not null check (
+    entity in (
+      'flashcard_sets',
+      'flashcards',
+      'set_user',
+      'class_set',
+      'class_user',
+      'card_history'
+    )
+  ),
+  op TEXT not null check (op in ('upsert', 'delete')),
+  set_id INTEGER,
+  card_id INTEGER,
+  class_id INTEGER,
+  user_id INTEGER,
+  -- the writing transaction, /api/sync orders by it so a change can't commit behind a cursor
+  tx_id BIGINT not null default pg_current_xact_id()::text::bigint,
+  changed_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (id)
+);
+
+create index change_log_tx_id on change_log (tx_id, id);
+
+create index change_log_changed_at on change_log (changed_at);
+
+-- one row, the newest transaction whose changes were pruned. Cursors at or before it are expired
+create table change_log_pruned (
+  id BOOLEAN default true check (id),
+  tx_id BIGINT not null,
+  primary key (id)
+);
+
+-- cards created through /api/sync by the device's client_id, so a retried push gets back the
+-- card it already made. card_id goes null once the card is deleted
+create table sync_creates (
+  user_id INTEGER not null,
+  client_id TEXT not null,
+  card_id INTEGER,
+  created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (user_id, client_id),
+  foreign KEY (user_id) references users (id) on delete CASCADE on update CASCADE,
+  foreign KEY (card_id) references flashcards (id) on delete SET NULL on update CASCADE
+);
+
+create table quizzes (
+  id SERIAL,
+  user_id INTEGER not null,
+  set_id INTEGER not null,
+  seed BIGINT not null,
+  created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (id),
+  foreign KEY (user_id) references users (id) on delete CASCADE on update CASCADE,
+  foreign KEY (set_id) references flashcard_sets (id) on delete CASCADE on update CASCADE
+);
+
+create table quiz_questions (
+  quiz_id INTEGER not null,
+  position INTEGER not null,
+  card_id INTEGER not null,
+  prompt TEXT not null,
+  choices TEXT[] not null,
+  answer_index INTEGER not null,
+  chosen_index INTEGER,
+  answered_at TIMESTAMP,
+  primary key (quiz_id, position),
+  foreign KEY (quiz_id) references quizzes (id) on delete CASCADE on update CASCADE,
+  foreign KEY (card_id) references flashcards (id) on delete CASCADE on update CASCADE
+);
+
+create table tests (
+  id SERIAL,
+  class_id INTEGER not null,
+  test_name TEXT not null,
+  question_count INTEGER not null default 20 check (question_count > 0),
+  time_limit_seconds INTEGER not null check (time_limit_seconds > 0),
+  max_attempts INTEGER not null default 1 check (max_attempts > 0),
+  created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  updated_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (id),
+  foreign KEY (class_id) references classes (id) on delete CASCADE on update CASCADE
+);
+
+create table test_sets (
+  test_id INTEGER not null,
+  set_id INTEGER not null,
+  primary key (test_id, set_id),
+  foreign KEY (test_id) references tests (id) on delete CASCADE on update CASCADE,
+  foreign KEY (set_id) references flashcard_sets (id) on delete CASCADE on update CASCADE
+);
+
+-- attempt numbers a student's sittings of a test from 1, the unique key stops two
+-- starts at once from both getting the last attempt
+create table test_attempts (
+  id SERIAL,
+  test_id INTEGER not null,
+  user_id INTEGER not null,
+  attempt INTEGER not null,
+  seed BIGINT not null,
+  total INTEGER not null,
+  score INTEGER,
+  started_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  deadline TIMESTAMP not null,
+  submitted_at TIMESTAMP,
+  primary key (id),
+  unique (test_id, user_id, attempt),
+  foreign KEY (test_id) references tests (id) on delete CASCADE on update CASCADE,
+  foreign KEY (user_id) references users (id) on delete CASCADE on update CASCADE
+);
+
+-- questions are copied into the attempt so editing or deleting a card doesn't change a grade
+create table test_attempt_questions (
+  attempt_id INTEGER not null,
+  position INTEGER not null,
+  card_id INTEGER,
+  prompt TEXT not null,
+  choices TEXT[] not null,
+  answer_index INTEGER not null,
+  chosen_index INTEGER,
+  primary key (attempt_id, position),
+  foreign KEY (attempt_id) references test_attempts (id) on delete CASCADE on update CASCADE,
+  foreign KEY (card_id) references flashcards (id) on delete SET NULL on update CASCADE
+);
+
+create table assignments (
+  id SERIAL,
+  class_id INTEGER not null,
+  set_id INTEGER not null,
+  due_at TIMESTAMP not null,
+  required_mastery_pct INTEGER check (required_mastery_pct between 1 and 100),
+  min_reviews INTEGER check (min_reviews > 0),
+  instructions TEXT,
+  created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  updated_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (id),
+  check (required_mastery_pct is not null or min_reviews is not null),
+  foreign KEY (class_id) references classes (id) on delete CASCADE on update CASCADE,
+  foreign KEY (class_id, set_id) references class_set (class_id, set_id) on delete CASCADE on update CASCADE
+);
+
+-- kept out of classes so the public class list never carries the code. Codes are 12 hex
+-- digits (48 random bits) so they can't be found by trying them against JoinClass
+create table class_join_codes (
+  class_id INTEGER not null,
+  code TEXT not null unique default UPPER(SUBSTR(REPLACE(gen_random_uuid()::text, '-', ''), 1, 12)),
+  enabled BOOLEAN not null default true,
+  expires_at TIMESTAMP,
+  updated_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (class_id),
+  foreign KEY (class_id) references classes (id) on delete CASCADE on update CASCADE
+);
+
+create table class_invites (
+  token TEXT not null,
+  class_id INTEGER not null,
+  expires_at TIMESTAMP not null,
+  created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (token),
+  foreign KEY (class_id) references classes (id) on delete CASCADE on update CASCADE
 );
\ No newline at end of file
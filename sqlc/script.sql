@@ -15,6 +15,11 @@ create table users (
   reset_token TEXT,
   last_login DATE not null default CURRENT_DATE,
   login_streak INTEGER not null default 1,
+  leaderboard_opt_in BOOLEAN not null default false,
+  leaderboard_visibility TEXT not null default 'full' check (
+    leaderboard_visibility in ('full', 'username', 'nickname', 'hidden')
+  ),
+  nickname_seed INTEGER not null default floor(random() * 2147483647)::int,
   created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
   updated_at TIMESTAMP not null default LOCALTIMESTAMP(2),
   check (LENGTH(password) >= 8),
@@ -25,16 +30,29 @@ create table flashcard_sets (
   id SERIAL,
   set_name TEXT not null,
   set_description TEXT not null,
+  mastery_streak INTEGER check (mastery_streak >= 0),
+  mastery_interval_days INTEGER check (mastery_interval_days >= 0),
+  typo_tolerance INTEGER not null default 1 check (typo_tolerance >= 0),
+  visibility TEXT not null default 'class' check (
+    visibility in ('private', 'class', 'unlisted', 'public')
+  ),
+  share_token TEXT unique,
+  forked_from INTEGER,
+  forked_at TIMESTAMP,
   created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
   updated_at TIMESTAMP not null default LOCALTIMESTAMP(2),
-  primary key (id)
+  primary key (id),
+  foreign KEY (forked_from) references flashcard_sets (id) on delete set null on update CASCADE
 ) TABLESPACE pg_default;
 
+-- upstream_id is the card a forked card was copied from. It has no foreign key so the fork
+-- can still tell when the upstream card is deleted
 create table flashcards (
   id SERIAL,
   front TEXT not null,
   back TEXT not null,
   set_id INTEGER not null,
+  upstream_id INTEGER,
   created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
   updated_at TIMESTAMP not null default LOCALTIMESTAMP(2),
   primary key (id),
@@ -45,6 +63,11 @@ create table classes (
   id SERIAL,
   class_name TEXT not null,
   class_description TEXT not null,
+  mastery_streak INTEGER check (mastery_streak >= 0),
+  mastery_interval_days INTEGER check (mastery_interval_days >= 0),
+  leaderboard_visibility TEXT not null default 'full' check (
+    leaderboard_visibility in ('full', 'username', 'nickname', 'hidden')
+  ),
   created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
   updated_at TIMESTAMP not null default LOCALTIMESTAMP(2),
   primary key (id)
@@ -56,6 +79,16 @@ create table card_history (
   score INTEGER default 0 not null,
   times_attempted INTEGER default 1 not null,
   is_mastered BOOLEAN not null default false,
+  ease_factor DOUBLE PRECISION not null default 2.5,
+  interval_days INTEGER not null default 0,
+  repetitions INTEGER not null default 0,
+  lapses INTEGER not null default 0,
+  correct_streak INTEGER not null default 0,
+  due_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  last_reviewed_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  last_grade TEXT check (last_grade in ('again', 'hard', 'good', 'easy')),
+  last_response_ms INTEGER check (last_response_ms >= 0),
+  last_answer TEXT,
   created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
   primary key (user_id, card_id),
   foreign KEY (user_id) references users (id) on delete CASCADE on update CASCADE,
@@ -90,6 +123,206 @@ create table set_user (
   foreign KEY (set_id) references flashcard_sets (id) on delete CASCADE on update CASCADE
 ) TABLESPACE pg_default;
 
+create table review_log (
+  id SERIAL,
+  user_id INTEGER not null,
+  card_id INTEGER not null,
+  grade TEXT not null check (grade in ('again', 'hard', 'good', 'easy')),
+  response_ms INTEGER check (response_ms >= 0),
+  answer TEXT,
+  interval_days INTEGER not null,
+  ease_factor DOUBLE PRECISION not null,
+  client_event_id TEXT,
+  reviewed_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (id),
+  unique (user_id, client_event_id),
+  foreign KEY (user_id) references users (id) on delete CASCADE on update CASCADE,
+  foreign KEY (card_id) references flashcards (id) on delete CASCADE on update CASCADE
+) TABLESPACE pg_default;
+
+create index review_log_user_reviewed_at on review_log (user_id, reviewed_at);
+
+-- one row per edit of a set or one of its cards, before_values and after_values hold the
+-- edited fields (null for a create or delete). card_id has no foreign key so a deleted
+-- card's history stays; user_id is set per transaction by the api and null if it wasn't
+create table revisions (
+  id SERIAL,
+  set_id INTEGER not null,
+  card_id INTEGER,
+  user_id INTEGER,
+  op TEXT not null check (op in ('create', 'update', 'delete')),
+  before_values JSONB,
+  after_values JSONB,
+  created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (id),
+  foreign KEY (set_id) references flashcard_sets (id) on delete CASCADE on update CASCADE,
+  foreign KEY (user_id) references users (id) on delete set null on update CASCADE
+) TABLESPACE pg_default;
+
+create index revisions_set_id on revisions (set_id, id);
+
+create table change_log (
+  id BIGSERIAL,
+  entity TEXT not null check (
+    entity in (
+      'flashcard_sets',
+      'flashcards',
+      'set_user',
+      'class_set',
+      'class_user',
+      'card_history'
+    )
+  ),
+  op TEXT not null check (op in ('upsert', 'delete')),
+  set_id INTEGER,
+  card_id INTEGER,
+  class_id INTEGER,
+  user_id INTEGER,
+  -- the writing transaction, /api/sync orders by it so a change can't commit behind a cursor
+  tx_id BIGINT not null default pg_current_xact_id()::text::bigint,
+  changed_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (id)
+) TABLESPACE pg_default;
+
+create index change_log_tx_id on change_log (tx_id, id);
+
+create index change_log_changed_at on change_log (changed_at);
+
+-- one row, the newest transaction whose changes were pruned. Cursors at or before it are expired
+create table change_log_pruned (
+  id BOOLEAN default true check (id),
+  tx_id BIGINT not null,
+  primary key (id)
+) TABLESPACE pg_default;
+
+-- cards created through /api/sync by the device's client_id, so a retried push gets back the
+-- card it already made. card_id goes null once the card is deleted
+create table sync_creates (
+  user_id INTEGER not null,
+  client_id TEXT not null,
+  card_id INTEGER,
+  created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (user_id, client_id),
+  foreign KEY (user_id) references users (id) on delete CASCADE on update CASCADE,
+  foreign KEY (card_id) references flashcards (id) on delete SET NULL on update CASCADE
+) TABLESPACE pg_default;
+
+create table quizzes (
+  id SERIAL,
+  user_id INTEGER not null,
+  set_id INTEGER not null,
+  seed BIGINT not null,
+  created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (id),
+  foreign KEY (user_id) references users (id) on delete CASCADE on update CASCADE,
+  foreign KEY (set_id) references flashcard_sets (id) on delete CASCADE on update CASCADE
+) TABLESPACE pg_default;
+
+create table quiz_questions (
+  quiz_id INTEGER not null,
+  position INTEGER not null,
+  card_id INTEGER not null,
+  prompt TEXT not null,
+  choices TEXT[] not null,
+  answer_index INTEGER not null,
+  chosen_index INTEGER,
+  answered_at TIMESTAMP,
+  primary key (quiz_id, position),
+  foreign KEY (quiz_id) references quizzes (id) on delete CASCADE on update CASCADE,
+  foreign KEY (card_id) references flashcards (id) on delete CASCADE on update CASCADE
+) TABLESPACE pg_default;
+
+create table tests (
+  id SERIAL,
+  class_id INTEGER not null,
+  test_name TEXT not null,
+  question_count INTEGER not null default 20 check (question_count > 0),
+  time_limit_seconds INTEGER not null check (time_limit_seconds > 0),
+  max_attempts INTEGER not null default 1 check (max_attempts > 0),
+  created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  updated_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (id),
+  foreign KEY (class_id) references classes (id) on delete CASCADE on update CASCADE
+) TABLESPACE pg_default;
+
+create table test_sets (
+  test_id INTEGER not null,
+  set_id INTEGER not null,
+  primary key (test_id, set_id),
+  foreign KEY (test_id) references tests (id) on delete CASCADE on update CASCADE,
+  foreign KEY (set_id) references flashcard_sets (id) on delete CASCADE on update CASCADE
+) TABLESPACE pg_default;
+
+-- attempt numbers a student's sittings of a test from 1, the unique key stops two
+-- starts at once from both getting the last attempt
+create table test_attempts (
+  id SERIAL,
+  test_id INTEGER not null,
+  user_id INTEGER not null,
+  attempt INTEGER not null,
+  seed BIGINT not null,
+  total INTEGER not null,
+  score INTEGER,
+  started_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  deadline TIMESTAMP not null,
+  submitted_at TIMESTAMP,
+  primary key (id),
+  unique (test_id, user_id, attempt),
+  foreign KEY (test_id) references tests (id) on delete CASCADE on update CASCADE,
+  foreign KEY (user_id) references users (id) on delete CASCADE on update CASCADE
+) TABLESPACE pg_default;
+
+-- questions are copied into the attempt so editing or deleting a card doesn't change a grade
+create table test_attempt_questions (
+  attempt_id INTEGER not null,
+  position INTEGER not null,
+  card_id INTEGER,
+  prompt TEXT not null,
+  choices TEXT[] not null,
+  answer_index INTEGER not null,
+  chosen_index INTEGER,
+  primary key (attempt_id, position),
+  foreign KEY (attempt_id) references test_attempts (id) on delete CASCADE on update CASCADE,
+  foreign KEY (card_id) references flashcards (id) on delete SET NULL on update CASCADE
+) TABLESPACE pg_default;
+
+create table assignments (
+  id SERIAL,
+  class_id INTEGER not null,
+  set_id INTEGER not null,
+  due_at TIMESTAMP not null,
+  required_mastery_pct INTEGER check (required_mastery_pct between 1 and 100),
+  min_reviews INTEGER check (min_reviews > 0),
+  instructions TEXT,
+  created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  updated_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (id),
+  check (required_mastery_pct is not null or min_reviews is not null),
+  foreign KEY (class_id) references classes (id) on delete CASCADE on update CASCADE,
+  foreign KEY (class_id, set_id) references class_set (class_id, set_id) on delete CASCADE on update CASCADE
+) TABLESPACE pg_default;
+
+-- kept out of classes so the public class list never carries the code. Codes are 12 hex
+-- digits (48 random bits) so they can't be found by trying them against JoinClass
+create table class_join_codes (
+  class_id INTEGER not null,
+  code TEXT not null unique default UPPER(SUBSTR(REPLACE(gen_random_uuid()::text, '-', ''), 1, 12)),
+  enabled BOOLEAN not null default true,
+  expires_at TIMESTAMP,
+  updated_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (class_id),
+  foreign KEY (class_id) references classes (id) on delete CASCADE on update CASCADE
+) TABLESPACE pg_default;
+
+create table class_invites (
+  token TEXT not null,
+  class_id INTEGER not null,
+  expires_at TIMESTAMP not null,
+  created_at TIMESTAMP not null default LOCALTIMESTAMP(2),
+  primary key (token),
+  foreign KEY (class_id) references classes (id) on delete CASCADE on update CASCADE
+) TABLESPACE pg_default;
+
 insert into
   users (username, email, password, first_name, last_name)
 values
@@ -304,6 +537,13 @@ values
   ('Economics 101', 'Basic economic principles'),
   ('Physics Lab', 'Practical physics experiments');
 
+insert into
+  class_join_codes (class_id)
+select
+  id
+from
+  classes;
+
 insert into
   class_user (user_id, class_id, role)
 values
@@ -436,4 +676,173 @@ $$ LANGUAGE plpgsql;
 create
 or replace trigger update_streak_trigger BEFORE
 update on users for EACH row when (NEW.last_login is distinct from OLD.last_login)
-execute PROCEDURE update_login_streak ();
\ No newline at end of file
+execute PROCEDURE update_login_streak ();
+
+-- feeds change_log for /api/sync. no foreign keys there so deletes survive as tombstones,
+-- and cascaded deletes fire this too
+create or replace function log_change () RETURNS TRIGGER
+set
+  SEARCH_PATH = public as $$
+
+DECLARE
+   r RECORD;
+   change TEXT;
+
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        r := OLD;
+        change := 'delete';
+    ELSE
+        r := NEW;
+        change := 'upsert';
+    END IF;
+
+    IF TG_TABLE_NAME = 'flashcard_sets' THEN
+        INSERT INTO change_log (entity, op, set_id, changed_at) VALUES (TG_TABLE_NAME, change, r.id, clock_timestamp());
+    ELSIF TG_TABLE_NAME = 'flashcards' THEN
+        -- a card moved to another set is gone from the old one
+        IF TG_OP = 'UPDATE' AND OLD.set_id <> NEW.set_id THEN
+            INSERT INTO change_log (entity, op, set_id, card_id, changed_at) VALUES (TG_TABLE_NAME, 'delete', OLD.set_id, OLD.id, clock_timestamp());
+        END IF;
+        INSERT INTO change_log (entity, op, set_id, card_id, changed_at) VALUES (TG_TABLE_NAME, change, r.set_id, r.id, clock_timestamp());
+    ELSIF TG_TABLE_NAME = 'set_user' THEN
+        INSERT INTO change_log (entity, op, set_id, user_id, changed_at) VALUES (TG_TABLE_NAME, change, r.set_id, r.user_id, clock_timestamp());
+    ELSIF TG_TABLE_NAME = 'class_set' THEN
+        INSERT INTO change_log (entity, op, set_id, class_id, changed_at) VALUES (TG_TABLE_NAME, change, r.set_id, r.class_id, clock_timestamp());
+    ELSIF TG_TABLE_NAME = 'class_user' THEN
+        INSERT INTO change_log (entity, op, class_id, user_id, changed_at) VALUES (TG_TABLE_NAME, change, r.class_id, r.user_id, clock_timestamp());
+    ELSIF TG_TABLE_NAME = 'card_history' THEN
+        INSERT INTO change_log (entity, op, card_id, user_id, changed_at) VALUES (TG_TABLE_NAME, change, r.card_id, r.user_id, clock_timestamp());
+    END IF;
+
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+create
+or replace trigger flashcard_sets_change_trigger
+after insert
+or
+update
+or delete on flashcard_sets for EACH row
+execute FUNCTION log_change ();
+
+create
+or replace trigger flashcards_change_trigger
+after insert
+or
+update
+or delete on flashcards for EACH row
+execute FUNCTION log_change ();
+
+create
+or replace trigger set_user_change_trigger
+after insert
+or
+update
+or delete on set_user for EACH row
+execute FUNCTION log_change ();
+
+create
+or replace trigger class_set_change_trigger
+after insert
+or
+update
+or delete on class_set for EACH row
+execute FUNCTION log_change ();
+
+create
+or replace trigger class_user_change_trigger
+after insert
+or
+update
+or delete on class_user for EACH row
+execute FUNCTION log_change ();
+
+create
+or replace trigger card_history_change_trigger
+after insert
+or
+update
+or delete on card_history for EACH row
+execute FUNCTION log_change ();
+
+-- records revisions for /api/flashcards/sets/revisions. The author is cowboy_cards.user_id,
+-- which the api sets for the transaction. Only the fields people edit are kept, so a write
+-- that changes none of them isn't a revision, and a deleted set takes its history with it
+create or replace function log_revision () RETURNS TRIGGER
+set
+  SEARCH_PATH = public as $$
+
+DECLARE
+   author INTEGER := NULLIF(current_setting('cowboy_cards.user_id', true), '')::int;
+   old_values JSONB;
+   new_values JSONB;
+
+BEGIN
+    IF TG_TABLE_NAME = 'flashcard_sets' THEN
+        IF TG_OP = 'DELETE' THEN
+            RETURN NULL;
+        END IF;
+
+        new_values := jsonb_build_object(
+            'set_name', NEW.set_name, 'set_description', NEW.set_description, 'mastery_streak', NEW.mastery_streak,
+            'mastery_interval_days', NEW.mastery_interval_days, 'typo_tolerance', NEW.typo_tolerance
+        );
+        IF TG_OP = 'INSERT' THEN
+            INSERT INTO revisions (set_id, user_id, op, after_values, created_at)
+            VALUES (NEW.id, author, 'create', new_values, clock_timestamp());
+            RETURN NULL;
+        END IF;
+
+        old_values := jsonb_build_object(
+            'set_name', OLD.set_name, 'set_description', OLD.set_description, 'mastery_streak', OLD.mastery_streak,
+            'mastery_interval_days', OLD.mastery_interval_days, 'typo_tolerance', OLD.typo_tolerance
+        );
+        IF old_values <> new_values THEN
+            INSERT INTO revisions (set_id, user_id, op, before_values, after_values, created_at)
+            VALUES (NEW.id, author, 'update', old_values, new_values, clock_timestamp());
+        END IF;
+        RETURN NULL;
+    END IF;
+
+    -- a card moved to another set is deleted from the old one and created in the new one.
+    -- Cards deleted along with their set are skipped, the set's history is going too
+    IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.set_id <> NEW.set_id) THEN
+        IF EXISTS (SELECT 1 FROM flashcard_sets WHERE id = OLD.set_id) THEN
+            INSERT INTO revisions (set_id, card_id, user_id, op, before_values, created_at)
+            VALUES (OLD.set_id, OLD.id, author, 'delete', jsonb_build_object('front', OLD.front, 'back', OLD.back), clock_timestamp());
+        END IF;
+        IF TG_OP = 'DELETE' THEN
+            RETURN NULL;
+        END IF;
+    END IF;
+
+    new_values := jsonb_build_object('front', NEW.front, 'back', NEW.back);
+    IF TG_OP = 'INSERT' OR OLD.set_id <> NEW.set_id THEN
+        INSERT INTO revisions (set_id, card_id, user_id, op, after_values, created_at)
+        VALUES (NEW.set_id, NEW.id, author, 'create', new_values, clock_timestamp());
+    ELSIF OLD.front <> NEW.front OR OLD.back <> NEW.back THEN
+        INSERT INTO revisions (set_id, card_id, user_id, op, before_values, after_values, created_at)
+        VALUES (NEW.set_id, NEW.id, author, 'update', jsonb_build_object('front', OLD.front, 'back', OLD.back), new_values, clock_timestamp());
+    END IF;
+
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+create
+or replace trigger flashcard_sets_revision_trigger
+after insert
+or
+update
+or delete on flashcard_sets for EACH row
+execute FUNCTION log_revision ();
+
+create
+or replace trigger flashcards_revision_trigger
+after insert
+or
+update
+or delete on flashcards for EACH row
+execute FUNCTION log_revision ();